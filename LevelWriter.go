@@ -29,11 +29,17 @@ type LevelWriter interface {
 var TRUE = []byte("true")
 var FALSE = []byte("false")
 
+//NewLevelWriter 返回一个指定等级的LevelWriter，可使用Logger.SetLevelWriter为某一个等级单独指定Writer与Pattern
+func NewLevelWriter(writer Writer, level Level, pattern Pattern) LevelWriter {
+	return newDefaultLevelWriter(writer, level, pattern)
+}
+
 func newDefaultLevelWriter(writer Writer, level Level, pattern Pattern) *DefaultLevelWriter {
 	lw := &DefaultLevelWriter{
 		buffer:  make([]byte, 256),
 		Pattern: pattern,
 		Writer:  writer,
+		level:   level,
 	}
 
 	lw.buffer = lw.Pattern.init(lw.buffer[:0])
@@ -49,6 +55,27 @@ type DefaultLevelWriter struct {
 	Writer          Writer
 	runtimeComputes *RunTimeComputes
 	origin          *DefaultLevelWriter
+	level           Level
+	errors          *errorReporter
+	dedupe          *dedupeSetting
+	stats           *loggerStats
+	//fields 需要去重时记录的每一项在缓存中的位置
+	fields []fieldPos
+}
+
+//setWriter 替换Writer，同时替换其来源的对象，保证之后clone出的对象使用新的Writer
+func (lw *DefaultLevelWriter) setWriter(writer Writer) {
+	for curr := lw; curr != nil; curr = curr.origin {
+		curr.Writer = writer
+	}
+}
+
+//appendKey 增加一项的key，需要去重时记录此项在缓存中的开始位置
+func (lw *DefaultLevelWriter) appendKey(key string) {
+	if lw.dedupe.load() != nil {
+		lw.fields = append(lw.fields, fieldPos{key, len(lw.buffer)})
+	}
+	lw.buffer = lw.Pattern.AppendKey(lw.buffer, key)
 }
 
 func (lw *DefaultLevelWriter) AddRuntime(r RunTimeCompute) LevelWriter {
@@ -83,21 +110,21 @@ func (lw *DefaultLevelWriter) AddStatic(name, value string) LevelWriter {
 }
 
 func (lw *DefaultLevelWriter) Hex(key string, value int) LevelWriter {
-	lw.buffer = lw.Pattern.AppendKey(lw.buffer, key)
+	lw.appendKey(key)
 	lw.buffer = lw.Pattern.AppendInt64(lw.buffer, int64(value), 16)
 
 	return lw
 }
 
 func (lw *DefaultLevelWriter) Bytes(key string, bytes []byte) LevelWriter {
-	lw.buffer = lw.Pattern.AppendKey(lw.buffer, key)
+	lw.appendKey(key)
 	lw.buffer = lw.Pattern.AppendString(lw.buffer, fmt.Sprintf("% X",bytes))
 
 	return lw
 }
 
 func (lw *DefaultLevelWriter) Error(error error) LevelWriter {
-	lw.buffer = lw.Pattern.AppendKey(lw.buffer, ErrorName)
+	lw.appendKey(ErrorName)
 	lw.buffer = lw.Pattern.AppendValue(lw.buffer, []byte(error.Error()))
 	return lw
 }
@@ -109,6 +136,10 @@ func (lw *DefaultLevelWriter) clone() LevelWriter {
 		Writer:          lw.Writer,
 		runtimeComputes: lw.runtimeComputes,
 		origin:          lw,
+		level:           lw.level,
+		errors:          lw.errors,
+		dedupe:          lw.dedupe,
+		stats:           lw.stats,
 	}
 
 	copy(result.buffer, lw.buffer[:len(lw.buffer)])
@@ -117,49 +148,49 @@ func (lw *DefaultLevelWriter) clone() LevelWriter {
 }
 
 func (lw *DefaultLevelWriter) Int(key string, value int) LevelWriter {
-	lw.buffer = lw.Pattern.AppendKey(lw.buffer, key)
+	lw.appendKey(key)
 	lw.buffer = lw.Pattern.AppendInt64(lw.buffer, int64(value), 10)
 
 	return lw
 }
 func (lw *DefaultLevelWriter) Int64(key string, value int64) LevelWriter {
-	lw.buffer = lw.Pattern.AppendKey(lw.buffer, key)
+	lw.appendKey(key)
 	lw.buffer = lw.Pattern.AppendInt64(lw.buffer, int64(value), 10)
 
 	return lw
 }
 func (lw *DefaultLevelWriter) Uint64(key string, value uint64) LevelWriter {
-	lw.buffer = lw.Pattern.AppendKey(lw.buffer, key)
+	lw.appendKey(key)
 	lw.buffer = lw.Pattern.AppendUint64(lw.buffer, value, 10)
 
 	return lw
 }
 func (lw *DefaultLevelWriter) Uint(key string, value uint) LevelWriter {
-	lw.buffer = lw.Pattern.AppendKey(lw.buffer, key)
+	lw.appendKey(key)
 	lw.buffer = lw.Pattern.AppendUint64(lw.buffer, uint64(value), 10)
 
 	return lw
 }
 func (lw *DefaultLevelWriter) String(key, value string) LevelWriter {
-	lw.buffer = lw.Pattern.AppendKey(lw.buffer, key)
+	lw.appendKey(key)
 	lw.buffer = lw.Pattern.AppendString(lw.buffer, value)
 
 	return lw
 }
 func (lw *DefaultLevelWriter) Float32(key string, value float32) LevelWriter {
-	lw.buffer = lw.Pattern.AppendKey(lw.buffer, key)
+	lw.appendKey(key)
 	lw.buffer = lw.Pattern.AppendFloat64(lw.buffer, float64(value))
 
 	return lw
 }
 func (lw *DefaultLevelWriter) Float64(key string, value float64) LevelWriter {
-	lw.buffer = lw.Pattern.AppendKey(lw.buffer, key)
+	lw.appendKey(key)
 	lw.buffer = lw.Pattern.AppendFloat64(lw.buffer, value)
 
 	return lw
 }
 func (lw *DefaultLevelWriter) Bool(key string, b bool) LevelWriter {
-	lw.buffer = lw.Pattern.AppendKey(lw.buffer, key)
+	lw.appendKey(key)
 	if b {
 		lw.buffer = lw.Pattern.AppendValue(lw.buffer, TRUE)
 	} else {
@@ -170,6 +201,11 @@ func (lw *DefaultLevelWriter) Bool(key string, b bool) LevelWriter {
 }
 
 func (lw *DefaultLevelWriter) Msg(message string) {
+	if d := lw.dedupe.load(); d != nil && d.suppress(lw, message) {
+		return
+	}
+	lw.stats.addRecord(lw.level)
+
 	buf := lw.buffer
 	pattern := lw.Pattern
 
@@ -184,10 +220,22 @@ func (lw *DefaultLevelWriter) Msg(message string) {
 	buf = pattern.AppendString(buf, message)
 	buf = pattern.Complete(buf)
 
-	_, _ = lw.Writer.Write(buf)
+	if _, err := writeLevel(lw.Writer, lw.level, buf); err != nil {
+		lw.errors.report(writerName(lw.Writer), "write", "", err)
+	}
+
+	if lw.level >= FlushLevel {
+		flushSoon(lw.Writer)
+	}
 }
 
 func (lw *DefaultLevelWriter) Msgf(message string, p ...interface{}) {
+	var msg = fmt.Sprintf(message, p...)
+	if d := lw.dedupe.load(); d != nil && d.suppress(lw, msg) {
+		return
+	}
+	lw.stats.addRecord(lw.level)
+
 	buf := lw.buffer
 	pattern := lw.Pattern
 
@@ -199,10 +247,16 @@ func (lw *DefaultLevelWriter) Msgf(message string, p ...interface{}) {
 	}
 
 	buf = pattern.AppendKey(buf, MessageName)
-	buf = pattern.AppendString(buf, fmt.Sprintf(message, p))
+	buf = pattern.AppendString(buf, msg)
 	buf = pattern.Complete(buf)
 
-	_, _ = lw.Writer.Write(buf)
+	if _, err := writeLevel(lw.Writer, lw.level, buf); err != nil {
+		lw.errors.report(writerName(lw.Writer), "write", "", err)
+	}
+
+	if lw.level >= FlushLevel {
+		flushSoon(lw.Writer)
+	}
 }
 
 type DisableLevelWriter struct {
@@ -1,11 +1,15 @@
 package onelog
 
 import (
+	"sync"
 	"time"
 )
 
 var logs = make(map[string]*Logger)
 
+//logsMutex 保护logs，WriteMetrics可能在其他协程中读取
+var logsMutex sync.RWMutex
+
 type Level uint8
 
 const (
@@ -34,6 +38,10 @@ var (
 	TimeFormat      = time.RFC3339
 	CallerName      = "caller"
 	ErrorName       = "err"
+	//DroppedName 采样汇总记录中被丢弃条数的项名称
+	DroppedName = "dropped"
+	//FlushLevel 等于或高于此等级的记录在Msg返回前将立即Flush，异步与网络的Writer只通知其尽快写出，不等待
+	FlushLevel = ErrorLevel
 )
 
 func (l Level) String() string {
@@ -61,6 +69,15 @@ type Logger struct {
 	writer   Writer
 	minLevel Level
 	pattern  Pattern
+	errors   *errorReporter
+	sampling *sampling
+	dedupe   *dedupeSetting
+	stats    *loggerStats
+	//settings 保护lws、writer与minLevel的修改，后台写入汇总记录时读取
+	settings sync.RWMutex
+	//base 由WithCallerSkip生成时为原Logger，所有操作都使用base
+	base       *Logger
+	callerSkip int
 }
 
 //NewLogger 返回一个新的Logger
@@ -70,11 +87,20 @@ func New(writer Writer, level Level, pattern Pattern) *Logger {
 		l = Disable
 	}
 
+	//终端格式按Writer决定是否使用颜色
+	if p, ok := pattern.(*ConsolePattern); ok {
+		p.detect(writer)
+	}
+
 	var log = &Logger{
 		lws:      make([]LevelWriter, 8),
 		writer:   writer,
 		minLevel: l,
 		pattern:  pattern,
+		errors:   &errorReporter{},
+		sampling: &sampling{},
+		dedupe:   &dedupeSetting{},
+		stats:    &loggerStats{},
 	}
 
 	log.refresh()
@@ -89,7 +115,11 @@ func (l *Logger) refresh() {
 		} else { //如果已经设置过的将保留
 			switch l.lws[i].(type) {
 			case nil, *DisableLevelWriter:
-				l.lws[i] = newDefaultLevelWriter(l.writer, i, l.pattern)
+				lw := newDefaultLevelWriter(l.writer, i, l.pattern)
+				lw.errors = l.errors
+				lw.dedupe = l.dedupe
+				lw.stats = l.stats
+				l.lws[i] = lw
 			}
 		}
 	}
@@ -97,6 +127,14 @@ func (l *Logger) refresh() {
 
 //AddStatic 给此Logger所有日志都增加一个静态
 func (l *Logger) AddStatic(name, value string) *Logger {
+	if l.base != nil {
+		l.base.AddStatic(name, value)
+		return l
+	}
+
+	l.settings.Lock()
+	defer l.settings.Unlock()
+
 	//循环调用
 	for i := TraceLevel; i <= PanicLevel; i++ {
 		if l.lws[i] != disableLevelWriter {
@@ -109,6 +147,14 @@ func (l *Logger) AddStatic(name, value string) *Logger {
 
 //AddRuntime 给此Logger所有日志都增加一个运行时记录
 func (l *Logger) AddRuntime(r RunTimeCompute) *Logger {
+	if l.base != nil {
+		l.base.AddRuntime(r)
+		return l
+	}
+
+	l.settings.Lock()
+	defer l.settings.Unlock()
+
 	//循环调用
 	for i := TraceLevel; i <= PanicLevel; i++ {
 		if l.lws[i] != disableLevelWriter {
@@ -119,78 +165,220 @@ func (l *Logger) AddRuntime(r RunTimeCompute) *Logger {
 	return l
 }
 
+//AddWriter 给此Logger增加一个Writer，记录将同时写入原有的Writer与此Writer。
+//可使用NewLevelFilter包装，只写入指定等级范围内的记录，如只将ERROR以上的记录写入错误文件
+func (l *Logger) AddWriter(writer Writer) *Logger {
+	if l.base != nil {
+		l.base.AddWriter(writer)
+		return l
+	}
+
+	if h, _ := l.errors.handler.Load().(ErrorHandler); h != nil {
+		setErrorHandler(writer, h)
+	}
+
+	l.settings.Lock()
+	defer l.settings.Unlock()
+
+	var old = l.writer
+	l.writer = NewMultipleWriter(old, writer)
+	//终端格式需要按新的Writer重新决定是否使用颜色
+	if p, ok := l.pattern.(*ConsolePattern); ok {
+		p.detect(l.writer)
+	}
+
+	//使用SetLevelWriter单独指定了Writer的等级保持不变
+	for i := TraceLevel; i <= PanicLevel; i++ {
+		if lw, ok := l.lws[i].(*DefaultLevelWriter); ok && lw.Writer == old {
+			lw.setWriter(l.writer)
+		}
+	}
+
+	return l
+}
+
+//SetErrorHandler 设置此Logger的错误处理，写入记录出错以及其所有Writer在后台出现的错误都将交给handler。
+//handler为nil时使用全局的错误处理
+func (l *Logger) SetErrorHandler(handler ErrorHandler) *Logger {
+	if l.base != nil {
+		l.base.SetErrorHandler(handler)
+		return l
+	}
+
+	l.errors.SetErrorHandler(handler)
+	setErrorHandler(l.writer, handler)
+
+	return l
+}
+
+//DumpRing 将此Logger中所有RingWriter保留的记录写出至writer。writer为nil时写出至RingWriter设置的DumpTo
+func (l *Logger) DumpRing(writer Writer) {
+	if l.base != nil {
+		l.base.DumpRing(writer)
+		return
+	}
+
+	eachWriter(l.writer, func(w Writer) {
+		if r, ok := w.(*RingWriter); ok {
+			if writer != nil {
+				r.Dump(writer)
+			} else {
+				r.dump()
+			}
+		}
+	})
+}
+
 func (l *Logger) Close() {
+	if l.base != nil {
+		l.base.Close()
+		return
+	}
+
+	//关闭前写入最后一次的采样与去重汇总
+	if l.sampling != nil && l.sampling.stop != nil {
+		l.stopSampleSummary()
+		l.writeSampleSummary()
+	}
+	if d := l.dedupe.swap(nil); d != nil {
+		d.close()
+	}
 	l.writer.Close()
 }
 
-//TraceLevel 返回一个Trace等级的日志对象。如果整体日志等级高于，则返回nil
-func (l *Logger) Trace() LevelWriter {
-	if l.minLevel > TraceLevel {
-		return &DisableLevelWriter{}
+//Flush 将Writer缓存中的内容立即写入
+func (l *Logger) Flush() {
+	if l.base != nil {
+		l.base.Flush()
+		return
 	}
 
-	return l.lws[TraceLevel].clone()
+	flush(l.writer)
+}
+
+//TraceLevel 返回一个Trace等级的日志对象。如果整体日志等级高于，则返回nil
+func (l *Logger) Trace() LevelWriter {
+	return l.levelWriter(TraceLevel)
 }
 
 //DebugLevel 返回一个Debug等级的日志对象。如果整体日志等级高于，则返回nil
 func (l *Logger) Debug() LevelWriter {
-	if l.minLevel > DebugLevel {
-		return &DisableLevelWriter{}
-	}
-
-	return l.lws[DebugLevel].clone()
+	return l.levelWriter(DebugLevel)
 }
 
 //InfoLevel 返回一个INFO等级的日志对象。如果整体日志等级高于，则返回nil
 func (l *Logger) Info() LevelWriter {
-	if l.minLevel > InfoLevel {
-		return &DisableLevelWriter{}
-	}
-
-	return l.lws[InfoLevel].clone()
+	return l.levelWriter(InfoLevel)
 }
 
 //WarnLevel 返回一个Warn等级的日志对象。如果整体日志等级高于，则返回nil
 func (l *Logger) Warn() LevelWriter {
-	if l.minLevel > WarnLevel {
-		return &DisableLevelWriter{}
-	}
-
-	return l.lws[WarnLevel].clone()
+	return l.levelWriter(WarnLevel)
 }
 
 //ErrorLevel 返回一个Error等级的日志对象。如果整体日志等级高于，则返回nil
 func (l *Logger) Error() LevelWriter {
-	if l.minLevel > ErrorLevel {
-		return &DisableLevelWriter{}
-	}
-
-	return l.lws[ErrorLevel].clone()
+	return l.levelWriter(ErrorLevel)
 }
 
 //FatalLevel 返回一个Fatal等级的日志对象。如果整体日志等级高于，则返回nil
 func (l *Logger) Fatal() LevelWriter {
-	if l.minLevel > FatalLevel {
-		return &DisableLevelWriter{}
-	}
-
-	return l.lws[FatalLevel].clone()
+	return l.levelWriter(FatalLevel)
 }
 
 //PanicLevel 返回一个Panic等级的日志对象。如果整体日志等级高于，则返回nil
 func (l *Logger) Panic() LevelWriter {
-	if l.minLevel > PanicLevel {
+	return l.levelWriter(PanicLevel)
+}
+
+//WithLevel 返回一个指定等级的日志对象，等级无效时返回DisableLevelWriter
+func (l *Logger) WithLevel(level Level) LevelWriter {
+	switch level {
+	case TraceLevel:
+		return l.Trace()
+	case DebugLevel:
+		return l.Debug()
+	case InfoLevel:
+		return l.Info()
+	case WarnLevel:
+		return l.Warn()
+	case ErrorLevel:
+		return l.Error()
+	case FatalLevel:
+		return l.Fatal()
+	case PanicLevel:
+		return l.Panic()
+	}
+
+	return disableLevelWriter
+}
+
+//Enabled 返回此等级的记录是否会被记录
+func (l *Logger) Enabled(level Level) bool {
+	if l.base != nil {
+		return l.base.Enabled(level)
+	}
+
+	return level <= PanicLevel && level >= l.minLevel
+}
+
+//levelWriter 返回一个指定等级的日志对象。由WithCallerSkip生成的Logger每次使用base当前的设置，并增加调用者跳过的层数
+func (l *Logger) levelWriter(level Level) LevelWriter {
+	if l.base != nil {
+		var lw = l.base.levelWriter(level)
+		if dlw, ok := lw.(*DefaultLevelWriter); ok {
+			dlw.runtimeComputes = dlw.runtimeComputes.withCallerSkip(l.callerSkip)
+		}
+		return lw
+	}
+
+	if l.minLevel > level || l.sampledOut(level) {
 		return &DisableLevelWriter{}
 	}
 
-	return l.lws[PanicLevel].clone()
+	return l.lws[level].clone()
+}
+
+//summaryWriter 返回后台写入汇总记录使用的LevelWriter，此等级未启用时返回nil
+func (l *Logger) summaryWriter(level Level) LevelWriter {
+	l.settings.RLock()
+	defer l.settings.RUnlock()
+
+	if l.minLevel > level {
+		return nil
+	}
+
+	return l.lws[level].clone()
+}
+
+//WithCallerSkip 返回一个增加了调用者跳过层数的日志对象，记录时使用此Logger当前的Writer、通用项与其他设置，
+//之后对此Logger的修改同样生效，对返回的Logger的设置也将作用于此Logger。
+//用于在其他日志接口的适配中调用Msg时，调用者信息仍为实际记录日志的位置
+func (l *Logger) WithCallerSkip(skip int) *Logger {
+	if l.base != nil {
+		return &Logger{base: l.base, callerSkip: l.callerSkip + skip}
+	}
+
+	return &Logger{base: l, callerSkip: skip}
 }
 
 //SetLevelWriter 设置指定等级的LevelWriter对象，如果参数给的是nil.则会替换成DisableLevelWriter对象。
 func (l *Logger) SetLevelWriter(level Level, leverWriter LevelWriter) *Logger {
+	if l.base != nil {
+		l.base.SetLevelWriter(level, leverWriter)
+		return l
+	}
+
+	l.settings.Lock()
+	defer l.settings.Unlock()
+
 	if leverWriter == nil {
 		l.lws[level] = &DisableLevelWriter{}
 	} else {
+		//去重设置由Logger的所有等级共用
+		if lw, ok := leverWriter.(*DefaultLevelWriter); ok && lw.dedupe == nil {
+			lw.dedupe = l.dedupe
+		}
 		l.lws[level] = leverWriter
 	}
 
@@ -199,6 +387,14 @@ func (l *Logger) SetLevelWriter(level Level, leverWriter LevelWriter) *Logger {
 
 //SetLevel 设置Log的记录等级
 func (l *Logger) SetLevel(level Level) *Logger {
+	if l.base != nil {
+		l.base.SetLevel(level)
+		return l
+	}
+
+	l.settings.Lock()
+	defer l.settings.Unlock()
+
 	l.minLevel = level
 	l.refresh()
 
@@ -207,11 +403,16 @@ func (l *Logger) SetLevel(level Level) *Logger {
 
 //SaveLogList 将一个日志对象存入日志列表当中
 func SaveLogList(name string, log *Logger) {
+	logsMutex.Lock()
 	logs[name] = log
+	logsMutex.Unlock()
 }
 
 //GetLog 将已经存入日志列表当中的日志对象取出,如果未找到将返回
 func GetLog(name string) *Logger {
+	logsMutex.RLock()
+	defer logsMutex.RUnlock()
+
 	return logs[name]
 }
 
@@ -46,14 +46,18 @@ func (t *TimeValue) GetName() string {
 }
 
 func (t *TimeValue) Values() []byte {
+	return timeValue(time.Now())
+}
+
+//timeValue 按TimeFormat格式化时间
+func timeValue(t time.Time) []byte {
 	buf := make([]byte, 0)
-	var now = time.Now()
 
 	if TimeFormat == "" {
-		return strconv.AppendInt(buf, now.Unix(), 10)
+		return strconv.AppendInt(buf, t.Unix(), 10)
 	}
 
-	return now.AppendFormat(buf, TimeFormat)
+	return t.AppendFormat(buf, TimeFormat)
 }
 
 //Caller 得到当前的调用者信息，可根据跳过值增加
@@ -67,6 +71,12 @@ func (*Caller) GetName() string {
 
 func (c *Caller) Values() []byte {
 	_, file, line, ok := runtime.Caller(c.CallerSkipFrameCount + 3)
+
+	return callerValue(file, line, ok)
+}
+
+//callerValue 调用者信息的格式为"文件 行号"
+func callerValue(file string, line int, ok bool) []byte {
 	var buf = make([]byte, len(file)+7)
 
 	if ok {
@@ -79,3 +89,34 @@ func (c *Caller) Values() []byte {
 
 	return buf
 }
+
+//withCallerSkip 返回一个新的链表，其中的Caller都增加skip层
+func (rs *RunTimeComputes) withCallerSkip(skip int) *RunTimeComputes {
+	if rs == nil {
+		return nil
+	}
+
+	var curr = rs.curr
+	if c, ok := curr.(*Caller); ok {
+		curr = &Caller{c.CallerSkipFrameCount + skip}
+	}
+
+	return &RunTimeComputes{curr, rs.next.withCallerSkip(skip)}
+}
+
+//withoutCaller 返回一个去掉了Caller的链表，没有Caller时返回原链表
+func (rs *RunTimeComputes) withoutCaller() *RunTimeComputes {
+	if rs == nil {
+		return nil
+	}
+
+	var next = rs.next.withoutCaller()
+	if _, ok := rs.curr.(*Caller); ok {
+		return next
+	}
+	if next == rs.next {
+		return rs
+	}
+
+	return &RunTimeComputes{rs.curr, next}
+}
@@ -0,0 +1,71 @@
+//Package adapters 将onelog.Logger适配为其他库使用的日志接口，如logr、grpclog以及只需要Print方法的数据库驱动
+package adapters
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/udbjqrmna/onelog"
+)
+
+//NameKey logr中WithName设置的名称写入的项名称
+var NameKey = "logger"
+
+//AppendKV 将key/value交替的参数写入LevelWriter，key不是string时使用fmt.Sprint，缺少value时为"(MISSING)"
+func AppendKV(lw onelog.LevelWriter, kv ...interface{}) onelog.LevelWriter {
+	for i := 0; i < len(kv); i += 2 {
+		var key, ok = kv[i].(string)
+		if !ok {
+			key = fmt.Sprint(kv[i])
+		}
+
+		if i+1 >= len(kv) {
+			lw = lw.String(key, "(MISSING)")
+			break
+		}
+		lw = appendValue(lw, key, kv[i+1])
+	}
+
+	return lw
+}
+
+//appendValue 按值的类型写入对应的项
+func appendValue(lw onelog.LevelWriter, key string, value interface{}) onelog.LevelWriter {
+	switch v := value.(type) {
+	case string:
+		return lw.String(key, v)
+	case int:
+		return lw.Int(key, v)
+	case int8:
+		return lw.Int64(key, int64(v))
+	case int16:
+		return lw.Int64(key, int64(v))
+	case int32:
+		return lw.Int64(key, int64(v))
+	case int64:
+		return lw.Int64(key, v)
+	case uint:
+		return lw.Uint(key, v)
+	case uint8:
+		return lw.Uint64(key, uint64(v))
+	case uint16:
+		return lw.Uint64(key, uint64(v))
+	case uint32:
+		return lw.Uint64(key, uint64(v))
+	case uint64:
+		return lw.Uint64(key, v)
+	case float32:
+		return lw.Float32(key, v)
+	case float64:
+		return lw.Float64(key, v)
+	case bool:
+		return lw.Bool(key, v)
+	case []byte:
+		return lw.Bytes(key, v)
+	case time.Time:
+		return lw.String(key, v.Format(onelog.TimeFormat))
+	}
+
+	//error、fmt.Stringer以及其他类型
+	return lw.String(key, fmt.Sprint(value))
+}
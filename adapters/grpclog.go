@@ -0,0 +1,126 @@
+package adapters
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/udbjqrmna/onelog"
+	"google.golang.org/grpc/grpclog"
+)
+
+//GrpcLogger 写入onelog.Logger的grpclog.LoggerV2，同时实现grpclog.DepthLoggerV2使调用者信息为grpc内实际记录的位置。
+//Fatal在写入后调用os.Exit(1)
+type GrpcLogger struct {
+	logger    *onelog.Logger
+	verbosity int
+	//loggers 按跳过层数缓存的日志对象
+	loggers sync.Map
+}
+
+var _ grpclog.LoggerV2 = (*GrpcLogger)(nil)
+var _ grpclog.DepthLoggerV2 = (*GrpcLogger)(nil)
+
+//NewGrpcLogger 返回一个写入logger的GrpcLogger，verbosity为V方法允许的最大值
+func NewGrpcLogger(logger *onelog.Logger, verbosity int) *GrpcLogger {
+	return &GrpcLogger{
+		logger:    logger,
+		verbosity: verbosity,
+	}
+}
+
+//SetGrpcLogger 将grpc的日志写入logger
+func SetGrpcLogger(logger *onelog.Logger, verbosity int) {
+	grpclog.SetLoggerV2(NewGrpcLogger(logger, verbosity))
+}
+
+//write skip为调用write的方法至实际记录位置之间的层数
+func (g *GrpcLogger) write(skip int, level onelog.Level, msg string) {
+	var logger, ok = g.loggers.Load(skip)
+	if !ok {
+		logger, _ = g.loggers.LoadOrStore(skip, g.logger.WithCallerSkip(skip+1))
+	}
+
+	logger.(*onelog.Logger).WithLevel(level).Msg(msg)
+
+	if level == onelog.FatalLevel {
+		g.logger.Flush()
+		os.Exit(1)
+	}
+}
+
+//sprintln 与fmt.Sprintln相同，去掉最后的换行
+func sprintln(args []interface{}) string {
+	return strings.TrimSuffix(fmt.Sprintln(args...), "\n")
+}
+
+func (g *GrpcLogger) Info(args ...interface{}) {
+	g.write(2, onelog.InfoLevel, fmt.Sprint(args...))
+}
+
+func (g *GrpcLogger) Infoln(args ...interface{}) {
+	g.write(2, onelog.InfoLevel, sprintln(args))
+}
+
+func (g *GrpcLogger) Infof(format string, args ...interface{}) {
+	g.write(2, onelog.InfoLevel, fmt.Sprintf(format, args...))
+}
+
+func (g *GrpcLogger) Warning(args ...interface{}) {
+	g.write(2, onelog.WarnLevel, fmt.Sprint(args...))
+}
+
+func (g *GrpcLogger) Warningln(args ...interface{}) {
+	g.write(2, onelog.WarnLevel, sprintln(args))
+}
+
+func (g *GrpcLogger) Warningf(format string, args ...interface{}) {
+	g.write(2, onelog.WarnLevel, fmt.Sprintf(format, args...))
+}
+
+func (g *GrpcLogger) Error(args ...interface{}) {
+	g.write(2, onelog.ErrorLevel, fmt.Sprint(args...))
+}
+
+func (g *GrpcLogger) Errorln(args ...interface{}) {
+	g.write(2, onelog.ErrorLevel, sprintln(args))
+}
+
+func (g *GrpcLogger) Errorf(format string, args ...interface{}) {
+	g.write(2, onelog.ErrorLevel, fmt.Sprintf(format, args...))
+}
+
+func (g *GrpcLogger) Fatal(args ...interface{}) {
+	g.write(2, onelog.FatalLevel, fmt.Sprint(args...))
+}
+
+func (g *GrpcLogger) Fatalln(args ...interface{}) {
+	g.write(2, onelog.FatalLevel, sprintln(args))
+}
+
+func (g *GrpcLogger) Fatalf(format string, args ...interface{}) {
+	g.write(2, onelog.FatalLevel, fmt.Sprintf(format, args...))
+}
+
+//V grpc的详细程度，小于等于verbosity时记录
+func (g *GrpcLogger) V(l int) bool {
+	return l <= g.verbosity
+}
+
+//InfoDepth depth为0时调用者为调用grpclog.InfoDepth的位置
+func (g *GrpcLogger) InfoDepth(depth int, args ...interface{}) {
+	g.write(2+depth, onelog.InfoLevel, sprintln(args))
+}
+
+func (g *GrpcLogger) WarningDepth(depth int, args ...interface{}) {
+	g.write(2+depth, onelog.WarnLevel, sprintln(args))
+}
+
+func (g *GrpcLogger) ErrorDepth(depth int, args ...interface{}) {
+	g.write(2+depth, onelog.ErrorLevel, sprintln(args))
+}
+
+func (g *GrpcLogger) FatalDepth(depth int, args ...interface{}) {
+	g.write(2+depth, onelog.FatalLevel, sprintln(args))
+}
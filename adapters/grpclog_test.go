@@ -0,0 +1,34 @@
+package adapters
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/udbjqrmna/onelog"
+	"google.golang.org/grpc/grpclog"
+)
+
+func TestGrpcLogger(t *testing.T) {
+	var buf bytes.Buffer
+	SetGrpcLogger(newTestLogger(&buf, onelog.InfoLevel), 1)
+
+	var caller = line()
+	grpclog.Infof("dial %s", "localhost")
+	var depthCaller = line()
+	grpclog.WarningDepth(0, "retry", 2)
+
+	var lines = strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("%s", buf.String())
+	}
+	if !strings.Contains(lines[0], `"msg":"dial localhost"`) || !strings.Contains(lines[0], caller) {
+		t.Errorf("%s", lines[0])
+	}
+	if !strings.Contains(lines[1], `"WARN"`) || !strings.Contains(lines[1], `"msg":"retry 2"`) || !strings.Contains(lines[1], depthCaller) {
+		t.Errorf("%s", lines[1])
+	}
+	if !grpclog.V(1) || grpclog.V(2) {
+		t.Error("V不正确")
+	}
+}
@@ -0,0 +1,103 @@
+package adapters
+
+import (
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/udbjqrmna/onelog"
+)
+
+//logrCallerSkip LogrSink的方法至Msg之间的层数
+const logrCallerSkip = 2
+
+//LogrSink 写入onelog.Logger的logr.LogSink，V(0)为INFO，V(1)为DEBUG，更高为TRACE。
+//WithName设置的名称以"/"连接写入NameKey项
+type LogrSink struct {
+	base   *onelog.Logger
+	logger *onelog.Logger
+	depth  int
+	name   string
+	values []interface{}
+}
+
+//NewLogr 返回一个写入logger的logr.Logger
+func NewLogr(logger *onelog.Logger) logr.Logger {
+	return logr.New(NewLogrSink(logger))
+}
+
+//NewLogrSink 返回一个写入logger的LogrSink
+func NewLogrSink(logger *onelog.Logger) *LogrSink {
+	return &LogrSink{
+		base:   logger,
+		logger: logger.WithCallerSkip(logrCallerSkip),
+	}
+}
+
+//LogrLevel 将logr的V等级转换为onelog的等级
+func LogrLevel(v int) onelog.Level {
+	switch {
+	case v <= 0:
+		return onelog.InfoLevel
+	case v == 1:
+		return onelog.DebugLevel
+	}
+
+	return onelog.TraceLevel
+}
+
+//Init 记录logr自身增加的调用层数
+func (s *LogrSink) Init(info logr.RuntimeInfo) {
+	s.depth = info.CallDepth
+	s.logger = s.base.WithCallerSkip(logrCallerSkip + s.depth)
+}
+
+func (s *LogrSink) Enabled(level int) bool {
+	return s.logger.Enabled(LogrLevel(level))
+}
+
+func (s *LogrSink) Info(level int, msg string, keysAndValues ...interface{}) {
+	s.write(s.logger.WithLevel(LogrLevel(level)), msg, keysAndValues)
+}
+
+func (s *LogrSink) Error(err error, msg string, keysAndValues ...interface{}) {
+	var lw = s.logger.Error()
+	if err != nil {
+		lw = lw.Error(err)
+	}
+	s.write(lw, msg, keysAndValues)
+}
+
+func (s *LogrSink) write(lw onelog.LevelWriter, msg string, keysAndValues []interface{}) {
+	if s.name != "" {
+		lw = lw.String(NameKey, s.name)
+	}
+	lw = AppendKV(lw, s.values...)
+	AppendKV(lw, keysAndValues...).Msg(msg)
+}
+
+func (s *LogrSink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	var result = *s
+	result.values = append(s.values[:len(s.values):len(s.values)], keysAndValues...)
+
+	return &result
+}
+
+func (s *LogrSink) WithName(name string) logr.LogSink {
+	var result = *s
+	if s.name == "" {
+		result.name = name
+	} else {
+		result.name = strings.Join([]string{s.name, name}, "/")
+	}
+
+	return &result
+}
+
+//WithCallDepth 实现logr.CallDepthLogSink，调用者信息再跳过depth层
+func (s *LogrSink) WithCallDepth(depth int) logr.LogSink {
+	var result = *s
+	result.depth = s.depth + depth
+	result.logger = s.base.WithCallerSkip(logrCallerSkip + result.depth)
+
+	return &result
+}
@@ -0,0 +1,81 @@
+package adapters
+
+import (
+	"bytes"
+	"errors"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/udbjqrmna/onelog"
+)
+
+//newTestLogger 返回一个记录调用者的Logger
+func newTestLogger(buf *bytes.Buffer, level onelog.Level) *onelog.Logger {
+	var logger = onelog.New(&onelog.Stdout{Writer: buf}, level, &onelog.JsonPattern{})
+	logger.AddRuntime(&onelog.Caller{})
+
+	return logger
+}
+
+//line 返回调用line的行号
+func line() string {
+	_, file, n, _ := runtime.Caller(1)
+	return file + " " + strconv.Itoa(n+1)
+}
+
+func TestLogr(t *testing.T) {
+	var buf bytes.Buffer
+	var log = NewLogr(newTestLogger(&buf, onelog.DebugLevel)).WithName("client").WithName("watch").WithValues("ns", "default")
+
+	log.V(2).Info("hidden")
+	var caller = line()
+	log.V(1).Info("sync", "count", 3, "ok", true, "odd")
+	log.Error(errors.New("timeout"), "failed", "retry", uint8(2))
+
+	var lines = strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("V(2)不应记录:%s", buf.String())
+	}
+	for _, want := range []string{`"DEBUG"`, `"logger":"client/watch"`, `"ns":"default"`, `"count":3`, `"ok":true`, `"odd":"(MISSING)"`, `"msg":"sync"`, caller} {
+		if !strings.Contains(lines[0], want) {
+			t.Errorf("缺少%s:%s", want, lines[0])
+		}
+	}
+	for _, want := range []string{`"ERROR"`, `"err":timeout`, `"retry":2`} {
+		if !strings.Contains(lines[1], want) {
+			t.Errorf("缺少%s:%s", want, lines[1])
+		}
+	}
+}
+
+func TestPrintLogger(t *testing.T) {
+	var buf bytes.Buffer
+	var p = NewPrintLogger(newTestLogger(&buf, onelog.DebugLevel), onelog.WarnLevel)
+
+	var caller = line()
+	p.Println("bad", "packet")
+	if s := buf.String(); !strings.Contains(s, `"msg":"bad packet"`) || !strings.Contains(s, `"WARN"`) || !strings.Contains(s, caller) {
+		t.Errorf("%s", s)
+	}
+}
+
+func TestAdapterFollowsBase(t *testing.T) {
+	var buf, added bytes.Buffer
+	var logger = newTestLogger(&buf, onelog.DebugLevel)
+	var p = NewPrintLogger(logger, onelog.InfoLevel)
+
+	//创建适配之后对原Logger的设置同样生效
+	logger.AddWriter(&onelog.Stdout{Writer: &added})
+	logger.SetDedupe(time.Hour)
+	defer logger.SetDedupe(0)
+
+	var caller = line()
+	p.Print("once")
+	p.Print("once")
+	if s := added.String(); strings.Count(s, "once") != 1 || !strings.Contains(s, caller) {
+		t.Errorf("之后增加的Writer与去重应生效:%s", s)
+	}
+}
@@ -0,0 +1,34 @@
+package adapters
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/udbjqrmna/onelog"
+)
+
+//PrintLogger 以指定等级写入onelog.Logger，适用于只需要Print方法的日志接口，如go-sql-driver/mysql的Logger
+type PrintLogger struct {
+	logger *onelog.Logger
+	level  onelog.Level
+}
+
+//NewPrintLogger 返回一个以level等级写入logger的PrintLogger
+func NewPrintLogger(logger *onelog.Logger, level onelog.Level) *PrintLogger {
+	return &PrintLogger{
+		logger: logger.WithCallerSkip(1),
+		level:  level,
+	}
+}
+
+func (p *PrintLogger) Print(v ...interface{}) {
+	p.logger.WithLevel(p.level).Msg(fmt.Sprint(v...))
+}
+
+func (p *PrintLogger) Printf(format string, v ...interface{}) {
+	p.logger.WithLevel(p.level).Msg(fmt.Sprintf(format, v...))
+}
+
+func (p *PrintLogger) Println(v ...interface{}) {
+	p.logger.WithLevel(p.level).Msg(strings.TrimSuffix(fmt.Sprintln(v...), "\n"))
+}
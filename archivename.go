@@ -0,0 +1,184 @@
+package onelog
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//defaultArchiveName 默认的归档文件名模板，{label}按时间切分时为时间段的开始，否则为当天的年月日
+const defaultArchiveName = "{name}.{label}_{seq}.{ext}"
+
+//archiveNaming 归档文件的命名方式，值为空时使用默认的模板，并与日志文件在同一目录
+type archiveNaming struct {
+	template string
+	dir      string
+	hostname string
+}
+
+//SetArchiveName 设置归档文件名的模板，如"{name}-{yyyy}{MM}{dd}T{HH}-{seq}.{ext}"。
+//可使用的占位符有{name} {yyyy} {yy} {MM} {dd} {HH} {mm} {ss} {label} {seq} {ext} {hostname} {pid}，
+//模板中必须包含{seq}，不压缩时{ext}与其前面的点将被忽略。template为空时恢复默认的模板
+func (w *FileWriter) SetArchiveName(template string) error {
+	if err := checkArchiveName(template); err != nil {
+		return err
+	}
+
+	var hostname, _ = os.Hostname()
+
+	w.mutex.Lock()
+	w.naming.template = template
+	w.naming.hostname = hostname
+	w.date = ""
+	w.mutex.Unlock()
+
+	return nil
+}
+
+//checkArchiveName 检查模板中包含{seq}，且不包含路径
+func checkArchiveName(template string) error {
+	if template != "" && !strings.Contains(template, "{seq}") {
+		return NotNil("ArchiveName:{seq}")
+	}
+	if strings.ContainsAny(template, `/\`) {
+		return NotUnderstand("ArchiveName:" + template)
+	}
+
+	return nil
+}
+
+//SetArchiveDir 设置存放归档文件的目录，不存在时将创建。目录需要与日志文件在同一文件系统，dir为空时与日志文件在同一目录
+func (w *FileWriter) SetArchiveDir(dir string) error {
+	if dir != "" {
+		if err := checkDir(strings.TrimRight(dir, "/")); err != nil {
+			return err
+		}
+	}
+
+	w.mutex.Lock()
+	w.naming.dir = dir
+	w.date = ""
+	w.mutex.Unlock()
+
+	return nil
+}
+
+func (n archiveNaming) getTemplate() string {
+	if n.template == "" {
+		return defaultArchiveName
+	}
+
+	return n.template
+}
+
+func (n archiveNaming) getDir(fileName string) string {
+	if n.dir == "" {
+		return filepath.Dir(fileName)
+	}
+
+	return n.dir
+}
+
+//path 按模板生成归档文件的路径，t为归档内容的时间
+func (n archiveNaming) path(fileName string, t time.Time, label, seq, ext string) string {
+	var pairs = []string{
+		"{name}", filepath.Base(fileName),
+		"{yyyy}", t.Format("2006"),
+		"{yy}", t.Format("06"),
+		"{MM}", t.Format("01"),
+		"{dd}", t.Format("02"),
+		"{HH}", t.Format("15"),
+		"{mm}", t.Format("04"),
+		"{ss}", t.Format("05"),
+		"{label}", label,
+		"{seq}", seq,
+		"{hostname}", n.hostname,
+		"{pid}", strconv.Itoa(os.Getpid()),
+	}
+	if ext == "" {
+		pairs = append(pairs, ".{ext}", "", "{ext}", "")
+	} else {
+		pairs = append(pairs, "{ext}", ext)
+	}
+
+	return filepath.Join(n.getDir(fileName), strings.NewReplacer(pairs...).Replace(n.getTemplate()))
+}
+
+//pattern 返回归档文件所在的目录，以及匹配归档文件名的正则表达式
+func (n archiveNaming) pattern(fileName, ext string) (string, *regexp.Regexp) {
+	var pairs = []string{
+		`\{name\}`, regexp.QuoteMeta(filepath.Base(fileName)),
+		`\{yyyy\}`, `\d{4}`,
+		`\{yy\}`, `\d{2}`,
+		`\{MM\}`, `\d{2}`,
+		`\{dd\}`, `\d{2}`,
+		`\{HH\}`, `\d{2}`,
+		`\{mm\}`, `\d{2}`,
+		`\{ss\}`, `\d{2}`,
+		`\{label\}`, `\d+(T\d+)?`,
+		`\{seq\}`, `\d+`,
+		`\{hostname\}`, regexp.QuoteMeta(n.hostname),
+		//多进程时包含其他进程的归档文件
+		`\{pid\}`, `\d+`,
+	}
+	if ext == "" {
+		pairs = append(pairs, `\.\{ext\}`, "", `\{ext\}`, "")
+	} else {
+		pairs = append(pairs, `\{ext\}`, regexp.QuoteMeta(ext))
+	}
+
+	var expr = strings.NewReplacer(pairs...).Replace(regexp.QuoteMeta(n.getTemplate()))
+
+	return n.getDir(fileName), regexp.MustCompile("^" + expr + "$")
+}
+
+//uncompressedName 压缩前切分出的文件名。模板以扩展名结尾时去掉扩展名，否则增加.tmp
+func uncompressedName(archive, ext string) string {
+	switch {
+	case ext == "":
+		return archive
+	case strings.HasSuffix(archive, "."+ext):
+		return archive[:len(archive)-len(ext)-1]
+	}
+
+	return archive + ".tmp"
+}
+
+//scheduleLocation 返回按时间切分计划使用的时区，归档文件名中的时间与其一致
+func scheduleLocation(schedule RotateSchedule) *time.Location {
+	switch s := schedule.(type) {
+	case *HourlySchedule:
+		return location(s.Location)
+	case *DailySchedule:
+		return location(s.Location)
+	case *CronSchedule:
+		return location(s.location)
+	}
+
+	return time.Local
+}
+
+//listArchives 列出属于此日志文件的归档文件
+func listArchives(fileName string, codec Codec, naming archiveNaming) []archiveFile {
+	var dir, re = naming.pattern(fileName, codec.Extension())
+
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	var result = make([]archiveFile, 0, len(infos))
+	for _, info := range infos {
+		if info.IsDir() || !re.MatchString(info.Name()) {
+			continue
+		}
+
+		result = append(result, archiveFile{filepath.Join(dir, info.Name()), info.Size(), info.ModTime()})
+	}
+
+	return result
+}
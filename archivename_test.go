@@ -0,0 +1,85 @@
+package onelog
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestArchiveNaming(t *testing.T) {
+	var n = archiveNaming{template: "{name}-{yyyy}{MM}{dd}T{HH}-{hostname}-{pid}-{seq}.{ext}", dir: "/archive", hostname: "web1"}
+	var tm = time.Date(2021, 12, 31, 23, 0, 0, 0, time.UTC)
+	var pid = strconv.Itoa(os.Getpid())
+
+	var got = n.path("/logs/app.log", tm, "", "3", "gz")
+	if got != "/archive/app.log-20211231T23-web1-"+pid+"-3.gz" {
+		t.Error(got)
+	}
+	if got = n.path("/logs/app.log", tm, "", "3", ""); got != "/archive/app.log-20211231T23-web1-"+pid+"-3" {
+		t.Error(got)
+	}
+
+	//默认模板同时匹配原来只有月日的归档
+	_, re := (archiveNaming{}).pattern("logs/app.log", "gz")
+	if !re.MatchString("app.log.20211231_1.gz") || !re.MatchString("app.log.1231_1.gz") || !re.MatchString("app.log.20211231T23_1.gz") {
+		t.Error(re)
+	}
+
+	_, re = n.pattern("/logs/app.log", "gz")
+	if !re.MatchString("app.log-20220101T00-web1-123-12.gz") || re.MatchString("app.log-20220101T00-web2-123-12.gz") {
+		t.Error(re)
+	}
+
+	//默认模板与原有的命名一致
+	if got = (archiveNaming{}).path("logs/app.log", tm, "20211231", "1", "gz"); got != "logs/app.log.20211231_1.gz" {
+		t.Error(got)
+	}
+	if uncompressedName("a-1.gz", "gz") != "a-1" || uncompressedName("a-1", "gz") != "a-1.tmp" {
+		t.Error("压缩前的文件名不正确")
+	}
+}
+
+func TestArchiveDir(t *testing.T) {
+	var dir = t.TempDir()
+	var w = &FileWriter{}
+	err := w.SetConfig(map[string]interface{}{
+		"LogsRoot":    dir,
+		"FileName":    "app.log",
+		"MaxCapacity": float64(1),
+		"ArchiveName": "{name}-{yyyy}{MM}{dd}-{seq}.{ext}",
+		"ArchiveDir":  "archive",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//已存在的归档文件不会被覆盖
+	var day = time.Now().Format("20060102")
+	_ = ioutil.WriteFile(filepath.Join(dir, "archive", "app.log-"+day+"-1.gz"), nil, 0666)
+
+	_, _ = w.Write([]byte("content"))
+	w.Flush()
+	w.mutex.Lock()
+	w.rotate(true)
+	w.mutex.Unlock()
+	w.Close()
+
+	if !exists(filepath.Join(dir, "archive", "app.log-"+day+"-2.gz")) {
+		t.Error("未在归档目录中生成归档文件")
+	}
+	if archives := listArchives(filepath.Join(dir, "app.log"), w.codec, w.naming); len(archives) != 2 {
+		t.Errorf("预期2个归档文件，实际%d个", len(archives))
+	}
+
+	if err = (&FileWriter{}).SetConfig(map[string]interface{}{
+		"LogsRoot":    dir,
+		"FileName":    "x.log",
+		"MaxCapacity": float64(1),
+		"ArchiveName": "{name}-{yyyy}",
+	}); err == nil {
+		t.Error("缺少{seq}的模板应返回错误")
+	}
+}
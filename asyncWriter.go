@@ -0,0 +1,377 @@
+package onelog
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//OverflowPolicy 异步写入队列已满时的处理策略
+type OverflowPolicy uint8
+
+const (
+	//OverflowBlock 队列满时阻塞等待，直到有空位
+	OverflowBlock OverflowPolicy = iota
+	//OverflowBlockTimeout 队列满时阻塞等待，超过指定时间仍无空位则丢弃当前记录
+	OverflowBlockTimeout
+	//OverflowDropNewest 队列满时丢弃当前记录
+	OverflowDropNewest
+	//OverflowDropOldest 队列满时丢弃队列中最早的记录，为当前记录腾出位置
+	OverflowDropOldest
+	//OverflowDropBelowLevel 队列满时丢弃低于DropLevel等级的当前记录，其他等级阻塞等待
+	OverflowDropBelowLevel
+)
+
+var refOverflow = map[string]OverflowPolicy{
+	"block":            OverflowBlock,
+	"block-timeout":    OverflowBlockTimeout,
+	"drop-newest":      OverflowDropNewest,
+	"drop-oldest":      OverflowDropOldest,
+	"drop-below-level": OverflowDropBelowLevel,
+}
+
+type asyncRecord struct {
+	level Level
+	data  []byte
+}
+
+//AsyncWriter 异步写入的Writer。记录先放入一个有界的环形队列，由后台协程写入至实际的Writer，
+//调用方不会因为实际Writer的缓慢而被阻塞
+type AsyncWriter struct {
+	writer    Writer
+	records   []asyncRecord
+	head      int
+	count     int
+	policy    OverflowPolicy
+	timeout   time.Duration
+	dropLevel Level
+	dropped   uint64
+	closed    bool
+	busy      bool
+	//flushing 需要在写完当前的记录后对实际的Writer进行Flush
+	flushing bool
+	mutex    sync.Mutex
+	wake     chan struct{}
+	space    chan struct{}
+	idle     chan struct{}
+	done     chan struct{}
+	errorReporter
+	writerCounters
+}
+
+//NewAsyncWriter 返回一个新的AsyncWriter，size为队列可容纳的记录条数
+func NewAsyncWriter(writer Writer, size int, policy OverflowPolicy) *AsyncWriter {
+	if size <= 0 {
+		size = 1024
+	}
+
+	a := &AsyncWriter{
+		writer:    writer,
+		records:   make([]asyncRecord, size),
+		policy:    policy,
+		timeout:   100 * time.Millisecond,
+		dropLevel: WarnLevel,
+		wake:      make(chan struct{}, 1),
+		space:     make(chan struct{}),
+		idle:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+
+	go a.run()
+
+	return a
+}
+
+//SetTimeout 设置OverflowBlockTimeout策略下的最长等待时间
+func (a *AsyncWriter) SetTimeout(timeout time.Duration) *AsyncWriter {
+	a.mutex.Lock()
+	a.timeout = timeout
+	a.mutex.Unlock()
+
+	return a
+}
+
+//SetDropLevel 设置OverflowDropBelowLevel策略下的等级，低于此等级的记录在队列满时将被丢弃
+func (a *AsyncWriter) SetDropLevel(level Level) *AsyncWriter {
+	a.mutex.Lock()
+	a.dropLevel = level
+	a.mutex.Unlock()
+
+	return a
+}
+
+//Dropped 返回到目前为止被丢弃的记录条数
+func (a *AsyncWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&a.dropped)
+}
+
+func (a *AsyncWriter) Write(p []byte) (n int, err error) {
+	return a.WriteLevel(Disable, p)
+}
+
+//WriteLevel 将记录放入队列。未知等级的记录使用Disable，在OverflowDropBelowLevel策略下不会被丢弃
+func (a *AsyncWriter) WriteLevel(level Level, p []byte) (n int, err error) {
+	var timer *time.Timer
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+
+	a.mutex.Lock()
+	for a.count == len(a.records) && !a.closed {
+		switch a.policy {
+		case OverflowDropNewest:
+			a.mutex.Unlock()
+			atomic.AddUint64(&a.dropped, 1)
+			return len(p), nil
+		case OverflowDropOldest:
+			a.records[a.head] = asyncRecord{}
+			a.head = (a.head + 1) % len(a.records)
+			a.count--
+			atomic.AddUint64(&a.dropped, 1)
+			continue
+		case OverflowDropBelowLevel:
+			if level < a.dropLevel {
+				a.mutex.Unlock()
+				atomic.AddUint64(&a.dropped, 1)
+				return len(p), nil
+			}
+		case OverflowBlockTimeout:
+			if timer == nil {
+				timer = time.NewTimer(a.timeout)
+			}
+		}
+
+		//等待后台协程腾出空间
+		space := a.space
+		a.mutex.Unlock()
+
+		if timer != nil {
+			select {
+			case <-space:
+			case <-timer.C:
+				atomic.AddUint64(&a.dropped, 1)
+				return len(p), nil
+			}
+		} else {
+			<-space
+		}
+
+		a.mutex.Lock()
+	}
+
+	if a.closed {
+		a.mutex.Unlock()
+		atomic.AddUint64(&a.dropped, 1)
+		return 0, Closed("AsyncWriter")
+	}
+
+	a.addWritten(len(p))
+	var data = make([]byte, len(p))
+	copy(data, p)
+
+	a.records[(a.head+a.count)%len(a.records)] = asyncRecord{level, data}
+	a.count++
+	a.mutex.Unlock()
+
+	select {
+	case a.wake <- struct{}{}:
+	default:
+	}
+
+	return len(p), nil
+}
+
+//run 后台写入协程，每次取出队列中全部的记录后再写入实际的Writer
+func (a *AsyncWriter) run() {
+	defer close(a.done)
+
+	var batch = make([]asyncRecord, 0, len(a.records))
+
+	for {
+		a.mutex.Lock()
+		for a.count == 0 && !a.flushing && !a.closed {
+			a.mutex.Unlock()
+			<-a.wake
+			a.mutex.Lock()
+		}
+
+		if a.count == 0 && a.closed {
+			a.mutex.Unlock()
+			return
+		}
+
+		batch = batch[:0]
+		for ; a.count > 0; a.count-- {
+			batch = append(batch, a.records[a.head])
+			a.records[a.head] = asyncRecord{}
+			a.head = (a.head + 1) % len(a.records)
+		}
+
+		//通知所有等待的写入方已有空间
+		close(a.space)
+		a.space = make(chan struct{})
+		a.busy = true
+		var flushing = a.flushing
+		a.flushing = false
+		a.mutex.Unlock()
+
+		for i := range batch {
+			if _, err := writeLevel(a.writer, batch[i].level, batch[i].data); err != nil {
+				a.report(writerName(a.writer), "write", "", err)
+			}
+			batch[i] = asyncRecord{}
+		}
+		if flushing {
+			flushSoon(a.writer)
+		}
+
+		a.mutex.Lock()
+		a.busy = false
+		if a.count == 0 {
+			//通知所有等待Flush的调用方队列已写完
+			close(a.idle)
+			a.idle = make(chan struct{})
+		}
+		a.mutex.Unlock()
+	}
+}
+
+//Flush 等待队列中已有的记录全部写入实际的Writer后，再对实际的Writer进行Flush
+func (a *AsyncWriter) Flush() {
+	a.mutex.Lock()
+	for (a.count > 0 || a.busy) && !a.closed {
+		idle := a.idle
+		a.mutex.Unlock()
+		<-idle
+		a.mutex.Lock()
+	}
+	var closed = a.closed
+	a.mutex.Unlock()
+
+	if !closed {
+		flush(a.writer)
+	}
+}
+
+//flushSoon 通知后台协程写完已在队列中的记录后对实际的Writer进行Flush，不等待
+func (a *AsyncWriter) flushSoon() {
+	a.mutex.Lock()
+	a.flushing = true
+	a.mutex.Unlock()
+
+	select {
+	case a.wake <- struct{}{}:
+	default:
+	}
+}
+
+//Close 停止接收新的记录，等待队列中的记录全部写入后关闭实际的Writer
+func (a *AsyncWriter) Close() {
+	a.mutex.Lock()
+	if a.closed {
+		a.mutex.Unlock()
+		return
+	}
+	a.closed = true
+	close(a.space)
+	a.space = make(chan struct{})
+	close(a.idle)
+	a.idle = make(chan struct{})
+	a.mutex.Unlock()
+
+	select {
+	case a.wake <- struct{}{}:
+	default:
+	}
+
+	<-a.done
+	a.writer.Close()
+}
+
+//SetConfig 设置相关参数，已初始化的AsyncWriter返回Initialized
+func (a *AsyncWriter) SetConfig(config interface{}) error {
+	if config == nil {
+		return NotUnderstand("AsyncWriter:WriterPara")
+	}
+
+	a.mutex.Lock()
+	var running = a.done != nil
+	a.mutex.Unlock()
+	if running {
+		return Initialized("AsyncWriter")
+	}
+
+	var conf map[string]interface{}
+	switch config.(type) {
+	case map[string]interface{}:
+		conf = config.(map[string]interface{})
+	default:
+		return &MistakeType{"map[string]interface {} type", ""}
+	}
+
+	writer, err := newWriterFromConfig(conf)
+	if err != nil {
+		return err
+	}
+
+	var size = 1024
+	if val, ok := conf["QueueSize"]; ok {
+		switch val.(type) {
+		case float64:
+			size = int(val.(float64))
+			if size <= 0 {
+				return &MistakeType{"大于0", strconv.Itoa(size)}
+			}
+		default:
+			return &MistakeType{"number type", ""}
+		}
+	}
+
+	var policy = OverflowBlock
+	if val, ok := conf["Overflow"]; ok {
+		switch val.(type) {
+		case string:
+			if policy, ok = refOverflow[strings.ToLower(val.(string))]; !ok {
+				return NotUnderstand("Overflow:" + val.(string))
+			}
+		default:
+			return &MistakeType{"string type", ""}
+		}
+	}
+
+	var timeout = 100 * time.Millisecond
+	if val, ok := conf["Timeout"]; ok {
+		switch val.(type) {
+		case float64:
+			timeout = time.Duration(val.(float64)) * time.Millisecond
+		default:
+			return &MistakeType{"number type", ""}
+		}
+	}
+
+	var dropLevel = WarnLevel
+	if val, ok := conf["DropLevel"]; ok {
+		if dropLevel, err = parseLevel(val); err != nil {
+			return err
+		}
+	}
+
+	a.writer = writer
+	a.records = make([]asyncRecord, size)
+	a.head, a.count = 0, 0
+	a.policy = policy
+	a.timeout = timeout
+	a.dropLevel = dropLevel
+	a.wake = make(chan struct{}, 1)
+	a.space = make(chan struct{})
+	a.idle = make(chan struct{})
+	a.done = make(chan struct{})
+
+	go a.run()
+
+	return nil
+}
@@ -0,0 +1,153 @@
+package onelog
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+)
+
+//slowWriter 每次写入都会等待一段时间的Writer，用于模拟缓慢的磁盘
+type slowWriter struct {
+	mutex  sync.Mutex
+	buffer bytes.Buffer
+	delay  time.Duration
+	lines  int
+	closed bool
+}
+
+func (s *slowWriter) Write(p []byte) (n int, err error) {
+	time.Sleep(s.delay)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.lines++
+	return s.buffer.Write(p)
+}
+
+func (s *slowWriter) Close() {
+	s.mutex.Lock()
+	s.closed = true
+	s.mutex.Unlock()
+}
+
+func (*slowWriter) Flush() {
+}
+
+func (*slowWriter) SetConfig(interface{}) error {
+	return nil
+}
+
+func TestAsyncWriterDrain(t *testing.T) {
+	sw := &slowWriter{delay: time.Millisecond}
+	var log = New(NewAsyncWriter(sw, 8, OverflowBlock), InfoLevel, &JsonPattern{})
+
+	for i := 0; i < 100; i++ {
+		log.Info().Int("i", i).Msg("async")
+	}
+	log.Close()
+
+	if sw.lines != 100 {
+		t.Errorf("预期写入100条，实际%d条", sw.lines)
+	}
+	if !sw.closed {
+		t.Error("实际的Writer未被关闭")
+	}
+}
+
+func TestAsyncWriterDropNewest(t *testing.T) {
+	sw := &slowWriter{delay: 20 * time.Millisecond}
+	aw := NewAsyncWriter(sw, 2, OverflowDropNewest)
+
+	for i := 0; i < 50; i++ {
+		_, _ = aw.Write([]byte("record\n"))
+	}
+	aw.Close()
+
+	if aw.Dropped() == 0 {
+		t.Error("队列已满时应当丢弃记录")
+	}
+	if uint64(sw.lines)+aw.Dropped() != 50 {
+		t.Errorf("写入%d条，丢弃%d条，合计应为50条", sw.lines, aw.Dropped())
+	}
+}
+
+func TestAsyncWriterDropBelowLevel(t *testing.T) {
+	sw := &slowWriter{delay: 5 * time.Millisecond}
+	aw := NewAsyncWriter(sw, 1, OverflowDropBelowLevel).SetDropLevel(ErrorLevel)
+
+	for i := 0; i < 20; i++ {
+		_, _ = aw.WriteLevel(DebugLevel, []byte("debug\n"))
+		_, _ = aw.WriteLevel(ErrorLevel, []byte("error\n"))
+	}
+	aw.Close()
+
+	if n := bytes.Count(sw.buffer.Bytes(), []byte("error")); n != 20 {
+		t.Errorf("ERROR记录不应被丢弃，实际写入%d条", n)
+	}
+}
+
+func TestAsyncWriterConfig(t *testing.T) {
+	var aw = &AsyncWriter{}
+	err := aw.SetConfig(map[string]interface{}{
+		"Writer":     "console",
+		"WriterPara": map[string]interface{}{"Console": "stdout"},
+		"QueueSize":  float64(16),
+		"Overflow":   "drop-oldest",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if aw.policy != OverflowDropOldest || len(aw.records) != 16 {
+		t.Error("配置未生效")
+	}
+	if _, ok := aw.SetConfig(map[string]interface{}{"Writer": "console"}).(Initialized); !ok {
+		t.Error("再次设置应返回Initialized")
+	}
+	aw.Close()
+
+	if _, err = aw.Write([]byte("x")); err == nil {
+		t.Error("关闭后写入应返回错误")
+	}
+}
+
+func TestAsyncWriterFlush(t *testing.T) {
+	sw := &slowWriter{delay: 2 * time.Millisecond}
+	aw := NewAsyncWriter(sw, 64, OverflowBlock)
+	defer aw.Close()
+
+	for i := 0; i < 30; i++ {
+		_, _ = aw.Write([]byte("record\n"))
+	}
+	aw.Flush()
+
+	sw.mutex.Lock()
+	defer sw.mutex.Unlock()
+	if sw.lines != 30 {
+		t.Errorf("Flush后应已写入30条，实际%d条", sw.lines)
+	}
+}
+
+func TestAsyncWriterErrorDoesNotWait(t *testing.T) {
+	sw := &slowWriter{delay: 50 * time.Millisecond}
+	var log = New(NewAsyncWriter(sw, 64, OverflowBlock), InfoLevel, &JsonPattern{})
+	defer log.Close()
+
+	for i := 0; i < 5; i++ {
+		log.Info().Int("i", i).Msg("queued")
+	}
+
+	//ERROR记录只通知后台协程尽快写出，不等待队列写完
+	var start = time.Now()
+	log.Error().Msg("failed")
+	if d := time.Since(start); d > 20*time.Millisecond {
+		t.Errorf("ERROR记录等待了%v", d)
+	}
+
+	log.Flush()
+	sw.mutex.Lock()
+	defer sw.mutex.Unlock()
+	if sw.lines != 6 {
+		t.Errorf("预期写入6条，实际%d条", sw.lines)
+	}
+}
@@ -0,0 +1,170 @@
+package onelog
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//Codec 归档文件的压缩方式
+type Codec interface {
+	//Extension 归档文件的扩展名，不含点。为空时表示不压缩
+	Extension() string
+	//NewWriter 返回一个将压缩后的内容写入w的WriteCloser
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+}
+
+//LevelCodec 可指定压缩级别的Codec，配置文件中的CompressLevel通过WithLevel设置，级别超出范围时返回错误
+type LevelCodec interface {
+	WithLevel(level int) (Codec, error)
+}
+
+//NoCompression 不压缩，切分出的文件直接作为归档文件
+type NoCompression struct {
+}
+
+func (NoCompression) Extension() string {
+	return ""
+}
+
+func (NoCompression) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return nil, NotUnderstand("NoCompression")
+}
+
+//GzipCodec gzip压缩，Level为compress/gzip中的压缩级别。zstd压缩在子包onelog/zstd中，导入后即可使用
+type GzipCodec struct {
+	Level int
+}
+
+func (GzipCodec) Extension() string {
+	return "gz"
+}
+
+func (c GzipCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriterLevel(w, c.Level)
+}
+
+//WithLevel 压缩级别为1~9
+func (c GzipCodec) WithLevel(level int) (Codec, error) {
+	if level < gzip.BestSpeed || level > gzip.BestCompression {
+		return nil, NotUnderstand("CompressLevel:" + strconv.Itoa(level))
+	}
+
+	return GzipCodec{level}, nil
+}
+
+var refCodec = map[string]Codec{
+	"none": NoCompression{},
+	"gzip": GzipCodec{gzip.DefaultCompression},
+}
+
+//RegisterCodec 注册一个压缩方式，注册后可在配置文件的Compress项中使用。需要在加载配置之前调用
+func RegisterCodec(name string, codec Codec) {
+	refCodec[strings.ToLower(name)] = codec
+}
+
+//codecByName 按名称得到压缩方式，level大于等于0且压缩方式支持时使用此压缩级别，超出其范围时返回错误
+func codecByName(name string, level int) (Codec, error) {
+	var codec, ok = refCodec[strings.ToLower(name)]
+	if !ok {
+		return nil, NotUnderstand("Compress:" + name)
+	}
+
+	if lc, ok := codec.(LevelCodec); ok && level >= 0 {
+		return lc.WithLevel(level)
+	}
+
+	return codec, nil
+}
+
+//compressFile 使用codec将fileName压缩为dest
+func compressFile(codec Codec, fileName string, dest string) error {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	d, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+
+	cw, err := codec.NewWriter(d)
+	if err != nil {
+		_ = d.Close()
+		return err
+	}
+
+	if _, err = io.Copy(cw, file); err == nil {
+		err = cw.Close()
+	} else {
+		_ = cw.Close()
+	}
+	if e := d.Close(); err == nil {
+		err = e
+	}
+
+	return err
+}
+
+//ArchiveWorkers 后台压缩归档文件的最大协程数，需要在第一次切分之前设置
+var ArchiveWorkers = 2
+
+var (
+	archiveJobs chan func()
+	archiveOnce sync.Once
+	//archiveDeferred 等待中的任务已满时暂存的任务，由后台协程完成手上的任务后取出
+	archiveDeferred []func()
+	archiveMutex    sync.Mutex
+)
+
+//submitArchive 将压缩任务交给后台的协程池，不会阻塞。等待中的任务已满时暂存，避免短时间内大量切分时无限制地增加协程
+func submitArchive(job func()) {
+	archiveOnce.Do(func() {
+		var workers = ArchiveWorkers
+		if workers <= 0 {
+			workers = 1
+		}
+
+		archiveJobs = make(chan func(), 64)
+		for i := 0; i < workers; i++ {
+			go func() {
+				for job := range archiveJobs {
+					job()
+					for job = takeDeferredArchive(); job != nil; job = takeDeferredArchive() {
+						job()
+					}
+				}
+			}()
+		}
+	})
+
+	//与取出在同一个锁中判断，暂存时队列已满，之后取出的每一个任务完成后都会检查暂存的任务
+	archiveMutex.Lock()
+	select {
+	case archiveJobs <- job:
+	default:
+		archiveDeferred = append(archiveDeferred, job)
+	}
+	archiveMutex.Unlock()
+}
+
+//takeDeferredArchive 取出一个暂存的任务，没有时返回nil
+func takeDeferredArchive() func() {
+	archiveMutex.Lock()
+	defer archiveMutex.Unlock()
+
+	if len(archiveDeferred) == 0 {
+		return nil
+	}
+
+	var job = archiveDeferred[0]
+	archiveDeferred[0] = nil
+	archiveDeferred = archiveDeferred[1:]
+
+	return job
+}
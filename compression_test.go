@@ -0,0 +1,142 @@
+package onelog
+
+import (
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+//upperCodec 测试用的自定义压缩方式，将内容转为大写
+type upperCodec struct {
+}
+
+type upperWriter struct {
+	io.Writer
+}
+
+func (u upperWriter) Write(p []byte) (int, error) {
+	return u.Writer.Write([]byte(strings.ToUpper(string(p))))
+}
+
+func (upperWriter) Close() error {
+	return nil
+}
+
+func (upperCodec) Extension() string {
+	return "up"
+}
+
+func (upperCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return upperWriter{w}, nil
+}
+
+func TestArchiveCodecs(t *testing.T) {
+	RegisterCodec("upper", upperCodec{})
+
+	var read = map[string]func(f *os.File) (io.Reader, error){
+		"gz": func(f *os.File) (io.Reader, error) {
+			return gzip.NewReader(f)
+		},
+		"up": func(f *os.File) (io.Reader, error) {
+			return f, nil
+		},
+		"": func(f *os.File) (io.Reader, error) {
+			return f, nil
+		},
+	}
+
+	for _, c := range []struct {
+		compress string
+		ext      string
+		want     string
+	}{
+		{"gzip", "gz", "content"},
+		{"none", "", "content"},
+		{"upper", "up", "CONTENT"},
+	} {
+		var dir = t.TempDir()
+		var w = &FileWriter{}
+		err := w.SetConfig(map[string]interface{}{
+			"LogsRoot":      dir,
+			"FileName":      "c.log",
+			"MaxCapacity":   float64(1),
+			"Compress":      c.compress,
+			"CompressLevel": float64(3),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, _ = w.Write([]byte("content"))
+		w.Flush()
+		w.mutex.Lock()
+		w.rotate(true)
+		w.mutex.Unlock()
+		w.Close()
+
+		var archives = listArchives(filepath.Join(dir, "c.log"), w.codec, w.naming)
+		if len(archives) != 1 {
+			t.Fatalf("%s:预期1个归档文件，实际%d个", c.compress, len(archives))
+		}
+		if c.ext != "" && !strings.HasSuffix(archives[0].path, "."+c.ext) {
+			t.Errorf("%s:扩展名不正确 %s", c.compress, archives[0].path)
+		}
+
+		f, _ := os.Open(archives[0].path)
+		r, err := read[c.ext](f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := ioutil.ReadAll(r)
+		_ = f.Close()
+		if string(data) != c.want {
+			t.Errorf("%s:内容不正确 %q", c.compress, data)
+		}
+	}
+
+	if err := (&FileWriter{}).SetConfig(map[string]interface{}{
+		"FileName":    filepath.Join(t.TempDir(), "x.log"),
+		"MaxCapacity": float64(1),
+		"Compress":    "lz4",
+	}); err == nil {
+		t.Error("未注册的压缩方式应返回错误")
+	}
+
+	//压缩级别在加载配置时检查
+	for _, level := range []float64{0, 10, -2} {
+		if err := (&FileWriter{}).SetConfig(map[string]interface{}{
+			"FileName":      filepath.Join(t.TempDir(), "x.log"),
+			"MaxCapacity":   float64(1),
+			"CompressLevel": level,
+		}); err == nil {
+			t.Errorf("gzip压缩级别%v应返回错误", level)
+		}
+	}
+}
+
+//TestSubmitArchiveDoesNotBlock 压缩跟不上时提交任务不应等待，暂存的任务之后仍会执行
+func TestSubmitArchiveDoesNotBlock(t *testing.T) {
+	var release = make(chan struct{})
+	var wg sync.WaitGroup
+
+	var start = time.Now()
+	for i := 0; i < 200; i++ {
+		wg.Add(1)
+		submitArchive(func() {
+			<-release
+			wg.Done()
+		})
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("提交任务等待了%v", d)
+	}
+
+	close(release)
+	wg.Wait()
+}
@@ -26,6 +26,19 @@ func (e NotFoundFile) Error() string {
 	return "未找到指定的文件:" + string(e)
 }
 
+type Closed string
+
+func (e Closed) Error() string {
+	return string(e) + "已关闭"
+}
+
+//Initialized Writer已初始化并在运行中，不能再次使用SetConfig设置
+type Initialized string
+
+func (e Initialized) Error() string {
+	return string(e) + "已初始化，不能再次设置"
+}
+
 type MistakeType struct {
 	expected  string
 	practical string
@@ -185,13 +198,109 @@ func loadLogs(config map[string]interface{}) error {
 				return err
 			}
 
-			SaveLogList(r["Id"].(string), New(writer, refLevel["LogLevel"], pattern))
+			var log = New(writer, refLevel[r["LogLevel"].(string)], pattern)
+
+			//Routes内的每一项都是一个附加的Writer，可使用MinLevel与MaxLevel指定写入的等级
+			if routes, ok := r["Routes"]; ok {
+				switch routes.(type) {
+				case []interface{}:
+					for _, route := range routes.([]interface{}) {
+						rec, ok := route.(map[string]interface{})
+						if !ok {
+							return NotUnderstand("数组内值必须为json")
+						}
+
+						w, err := newWriterFromConfig(rec)
+						if err != nil {
+							return err
+						}
+						log.AddWriter(w)
+					}
+				default:
+					return NotUnderstand("Routes")
+				}
+			}
+
+			if err := loadSampling(log, r); err != nil {
+				return err
+			}
+			if err := loadDedupe(log, r); err != nil {
+				return err
+			}
+			if err := loadRateLimit(log, r); err != nil {
+				return err
+			}
+
+			SaveLogList(r["Id"].(string), log)
 		}
 	}
 
 	return nil
 }
 
+//newWriterFromConfig 根据一个包含Writer与WriterPara的配置节生成Writer对象
+func newWriterFromConfig(config map[string]interface{}) (Writer, error) {
+	name, ok := config["Writer"]
+	if !ok {
+		return nil, NotNil("Writer")
+	}
+	para, ok := config["WriterPara"]
+	if !ok {
+		return nil, NotNil("WriterPara")
+	}
+
+	switch name.(type) {
+	case string:
+	default:
+		return nil, &MistakeType{"string type", ""}
+	}
+
+	ref, ok := refWriter[strings.ToLower(name.(string))]
+	if !ok {
+		return nil, NotUnderstand("Writer:" + name.(string))
+	}
+
+	w := reflect.New(reflect.TypeOf(ref)).Interface().(Writer)
+	if err := w.SetConfig(para); err != nil {
+		return nil, err
+	}
+
+	//连续失败时暂停使用，或写入备用的Writer
+	w, err := faultTolerance(w, config)
+	if err != nil {
+		return nil, err
+	}
+
+	//指定了等级范围时只写入范围内的记录
+	if hasLevelRange(config) {
+		min, max, err := levelRange(config)
+		if err != nil {
+			return nil, err
+		}
+		return NewLevelFilter(w, min, max), nil
+	}
+
+	return w, nil
+}
+
+//parseLevel 将配置中的等级转换为Level，可使用名称或数字
+func parseLevel(val interface{}) (Level, error) {
+	switch val.(type) {
+	case string:
+		if l, ok := refLevel[strings.ToLower(val.(string))]; ok {
+			return l, nil
+		}
+		return Disable, NotUnderstand("Level:" + val.(string))
+	case float64:
+		if v := val.(float64); v >= float64(TraceLevel) && v <= float64(Disable) {
+			return Level(v), nil
+		}
+		return Disable, &MistakeType{"0..7", strconv.Itoa(int(val.(float64)))}
+	}
+
+	return Disable, NotUnderstand("Level")
+}
+
 var refLevel = make(map[string]Level)
 var refPattern = make(map[string]interface{})
 var refWriter = make(map[string]interface{})
@@ -210,11 +319,18 @@ func init() {
 	//初始化反射的WritePattern对象
 	refPattern["jsonpattern"] = JsonPattern{}
 	refPattern["old"] = OldPattern{}
+	refPattern["logfmt"] = LogfmtPattern{}
+	refPattern["console"] = ConsolePattern{}
 
 	//初始化反射的Writer对象
 	refWriter["console"] = Stdout{}
 	refWriter["file"] = FileWriter{}
 	refWriter["multiple"] = MultipleWriter{}
+	refWriter["async"] = AsyncWriter{}
+	refWriter["syslog"] = SyslogWriter{}
+	refWriter["net"] = NetWriter{}
+	refWriter["http"] = HTTPWriter{}
+	refWriter["ring"] = RingWriter{}
 
 }
 
@@ -0,0 +1,324 @@
+package onelog
+
+import (
+	"bytes"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+)
+
+//ColorMode ConsolePattern是否使用颜色
+type ColorMode uint8
+
+const (
+	//ColorAuto 写入终端且NO_COLOR环境变量为空时使用颜色
+	ColorAuto ColorMode = iota
+	//ColorAlways 总是使用颜色
+	ColorAlways
+	//ColorNever 不使用颜色
+	ColorNever
+)
+
+const (
+	//fieldSep 缓存中每一项的开始
+	fieldSep = 0x1e
+	//keySep 缓存中名称与值的分隔
+	keySep = 0x1f
+
+	colorReset = "\x1b[0m"
+	colorDim   = "\x1b[2m"
+	colorRed   = "\x1b[31m"
+)
+
+var levelColors = map[string]string{
+	TraceLevel.String(): "\x1b[90m",
+	DebugLevel.String(): "\x1b[36m",
+	InfoLevel.String():  "\x1b[32m",
+	WarnLevel.String():  "\x1b[33m",
+	ErrorLevel.String(): "\x1b[31m",
+	FatalLevel.String(): "\x1b[1;31m",
+	PanicLevel.String(): "\x1b[1;35m",
+}
+
+//ConsolePattern 适合开发时在终端中阅读的记录格式。时间、等级、调用者对齐显示，其后为消息，其他项以key=value淡色显示，
+//错误与包含换行的值在其后分行显示。Color为ColorAuto时，Logger最终写入的Writer都是写入终端的Stdout且NO_COLOR为空时使用颜色，
+//Writer可包装在MultipleWriter、AsyncWriter等之中，AddWriter后将重新判断
+type ConsolePattern struct {
+	Color ColorMode
+	//CallerWidth 调用者一栏的宽度，为0时使用20
+	CallerWidth int
+	//colored 为1时使用颜色，AddWriter时可能在写入的同时修改
+	colored uint32
+}
+
+//detect 按Writer与NO_COLOR环境变量决定是否使用颜色
+func (c *ConsolePattern) detect(writer Writer) {
+	var colored bool
+
+	switch c.Color {
+	case ColorAlways:
+		colored = true
+	case ColorNever:
+	default:
+		//按NO_COLOR的约定，值为空时视为未设置
+		if os.Getenv("NO_COLOR") == "" {
+			colored = toTerminal(writer)
+		}
+	}
+
+	if colored {
+		atomic.StoreUint32(&c.colored, 1)
+	} else {
+		atomic.StoreUint32(&c.colored, 0)
+	}
+}
+
+//toTerminal 包装的Writer之外，最终写入的Writer是否都是写入终端的Stdout。写入文件等时不使用颜色，避免其中出现颜色代码
+func toTerminal(writer Writer) bool {
+	var terminal, other bool
+
+	eachWriter(writer, func(w Writer) {
+		switch w := w.(type) {
+		case *MultipleWriter, *LevelFilter, *AsyncWriter, *HealthWriter, *FailoverWriter:
+		case *Stdout:
+			if f, ok := w.Writer.(*os.File); ok && isTerminal(f) {
+				terminal = true
+			} else {
+				other = true
+			}
+		default:
+			other = true
+		}
+	})
+
+	return terminal && !other
+}
+
+//isColored 是否使用颜色
+func (c *ConsolePattern) isColored() bool {
+	return atomic.LoadUint32(&c.colored) == 1
+}
+
+//isTerminal 文件是否为终端
+func isTerminal(f *os.File) bool {
+	var info, err = f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func (c *ConsolePattern) init(buffer []byte) []byte {
+	return buffer
+}
+
+//AppendKey 增加一个key的方法，先按项记录，在Complete时排列
+func (c *ConsolePattern) AppendKey(buffer []byte, key string) []byte {
+	buffer = append(buffer, fieldSep)
+	buffer = appendConsoleRaw(buffer, []byte(key))
+	return append(buffer, keySep)
+}
+
+//AppendValue 增加一个[]byte数组值的方法
+func (c *ConsolePattern) AppendValue(buffer []byte, value []byte) []byte {
+	return appendConsoleRaw(buffer, value)
+}
+
+//AppendUint64 将一个uint64值记录缓存中
+func (c *ConsolePattern) AppendUint64(buffer []byte, value uint64, base int) []byte {
+	return strconv.AppendUint(buffer, value, base)
+}
+
+//AppendUint32 将一个uint32值记录缓存中
+func (c *ConsolePattern) AppendUint32(buffer []byte, value uint32, base int) []byte {
+	return strconv.AppendUint(buffer, uint64(value), base)
+}
+
+//AppendFloat64 将一个float64值记录缓存中
+func (c *ConsolePattern) AppendFloat64(buffer []byte, val float64) []byte {
+	switch {
+	case math.IsNaN(val):
+		return append(buffer, "NaN"...)
+	case math.IsInf(val, 1):
+		return append(buffer, "+Inf"...)
+	case math.IsInf(val, -1):
+		return append(buffer, "-Inf"...)
+	}
+
+	return strconv.AppendFloat(buffer, val, 'f', -1, 64)
+}
+
+//AppendInt64 将一个int64的值插入至数据内
+func (c *ConsolePattern) AppendInt64(buffer []byte, value int64, base int) []byte {
+	return strconv.AppendInt(buffer, value, base)
+}
+
+//AppendString 将一个string的值插入至数据内
+func (c *ConsolePattern) AppendString(buffer []byte, value string) []byte {
+	return appendConsoleRaw(buffer, []byte(value))
+}
+
+func (c *ConsolePattern) addRuntimeValues(buffer []byte, r RunTimeCompute) []byte {
+	buffer = c.AppendKey(buffer, r.GetName())
+	buffer = c.AppendValue(buffer, r.Values())
+
+	return buffer
+}
+
+//appendConsoleRaw 写入原始的值，去掉其中与分隔符相同的字节
+func appendConsoleRaw(buffer []byte, value []byte) []byte {
+	for _, b := range value {
+		if b == fieldSep || b == keySep {
+			b = ' '
+		}
+		buffer = append(buffer, b)
+	}
+
+	return buffer
+}
+
+type consoleField struct {
+	key, value []byte
+}
+
+//Complete 将记录的各项排列为一行，错误与多行的值在其后分行显示。生成新的缓存，不修改LevelWriter中的内容
+func (c *ConsolePattern) Complete(buffer []byte) []byte {
+	var level, time, caller, message []byte
+	var fields, blocks []consoleField
+
+	for _, part := range bytes.Split(buffer, []byte{fieldSep}) {
+		var i = bytes.IndexByte(part, keySep)
+		if i < 0 {
+			continue
+		}
+		var f = consoleField{part[:i], part[i+1:]}
+
+		switch string(f.key) {
+		case LevelName:
+			level = f.value
+		case TimeName:
+			time = f.value
+		case CallerName:
+			caller = shortCaller(f.value)
+		case MessageName:
+			message = f.value
+		case ErrorName:
+			blocks = append(blocks, f)
+		default:
+			if bytes.IndexByte(f.value, '\n') >= 0 {
+				blocks = append(blocks, f)
+			} else {
+				fields = append(fields, f)
+			}
+		}
+	}
+
+	var out = make([]byte, 0, len(buffer)+64)
+	var colored = c.isColored()
+
+	if len(time) > 0 {
+		out = c.colorize(out, colorDim, time)
+		out = append(out, ' ')
+	}
+
+	out = c.colorize(out, levelColors[string(level)], level)
+	out = appendPadding(out, 5-len(level)+1)
+
+	if len(caller) > 0 {
+		var width = c.CallerWidth
+		if width <= 0 {
+			width = 20
+		}
+		out = c.colorize(out, colorDim, caller)
+		out = appendPadding(out, width-len(caller)+1)
+	}
+
+	//消息中的换行之后的内容分行显示
+	var rest []byte
+	if i := bytes.IndexByte(message, '\n'); i >= 0 {
+		message, rest = message[:i], message[i+1:]
+	}
+	out = append(out, message...)
+
+	for _, f := range fields {
+		out = append(out, ' ', ' ')
+		if colored {
+			out = append(out, colorDim...)
+		}
+		out = append(out, f.key...)
+		out = append(out, '=')
+		out = appendLogfmtValue(out, f.value)
+		if colored {
+			out = append(out, colorReset...)
+		}
+	}
+	out = append(out, '\n')
+
+	if len(rest) > 0 {
+		out = appendIndented(out, rest, 4)
+	}
+	for _, f := range blocks {
+		out = append(out, "    "...)
+		if bytes.Equal(f.key, []byte(ErrorName)) {
+			out = c.colorize(out, colorRed, f.key)
+		} else {
+			out = c.colorize(out, colorDim, f.key)
+		}
+		out = append(out, ": "...)
+
+		//之后的行与第一行对齐
+		var lines = bytes.Split(bytes.TrimRight(f.value, "\n"), []byte{'\n'})
+		out = append(out, lines[0]...)
+		out = append(out, '\n')
+		for _, line := range lines[1:] {
+			out = appendPadding(out, 6+len(f.key))
+			out = append(out, line...)
+			out = append(out, '\n')
+		}
+	}
+
+	return out
+}
+
+//colorize 使用颜色时在值的前后加入颜色代码
+func (c *ConsolePattern) colorize(buffer []byte, color string, value []byte) []byte {
+	if !c.isColored() || color == "" {
+		return append(buffer, value...)
+	}
+
+	buffer = append(buffer, color...)
+	buffer = append(buffer, value...)
+	return append(buffer, colorReset...)
+}
+
+//appendIndented 每一行前加入indent个空格
+func appendIndented(buffer []byte, value []byte, indent int) []byte {
+	for _, line := range bytes.Split(bytes.TrimRight(value, "\n"), []byte{'\n'}) {
+		buffer = appendPadding(buffer, indent)
+		buffer = append(buffer, line...)
+		buffer = append(buffer, '\n')
+	}
+
+	return buffer
+}
+
+func appendPadding(buffer []byte, n int) []byte {
+	if n < 1 {
+		n = 1
+	}
+	for i := 0; i < n; i++ {
+		buffer = append(buffer, ' ')
+	}
+
+	return buffer
+}
+
+//shortCaller 将Caller的"路径 行号"转换为"文件名:行号"
+func shortCaller(value []byte) []byte {
+	var i = bytes.LastIndexByte(value, ' ')
+	if i < 0 {
+		return value
+	}
+
+	var name = filepath.Base(string(value[:i]))
+	return append([]byte(name+":"), value[i+1:]...)
+}
@@ -0,0 +1,90 @@
+package onelog
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestConsolePattern(t *testing.T) {
+	var buf bytes.Buffer
+	var p = &ConsolePattern{}
+	var log = New(&Stdout{&buf}, DebugLevel, p)
+	log.AddRuntime(&Caller{})
+	if p.isColored() {
+		t.Error("写入非终端时不应使用颜色")
+	}
+
+	log.Warn().String("user", "a b").Int("n", 3).
+		Error(errors.New("open a.log\nno such file")).
+		String("stack", "main.main()\n\tmain.go:10").
+		Msg("login failed")
+
+	var lines = strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("%q", lines)
+	}
+	if !strings.Contains(lines[0], "WARN  consolePattern_test.go:") || !strings.Contains(lines[0], `login failed  user="a b"  n=3`) {
+		t.Errorf("%q", lines[0])
+	}
+	if strings.Contains(lines[0], LevelName+"=") || strings.Contains(lines[0], "\x1b") {
+		t.Errorf("%q", lines[0])
+	}
+	if lines[1] != "    "+ErrorName+": open a.log" || lines[2] != "         no such file" {
+		t.Errorf("错误应分行显示:%q", lines[1:3])
+	}
+	if lines[3] != "    stack: main.main()" || lines[4] != "           \tmain.go:10" {
+		t.Errorf("多行的值应分行显示:%q", lines[3:])
+	}
+}
+
+func TestConsolePatternColor(t *testing.T) {
+	var buf bytes.Buffer
+	var log = New(&Stdout{&buf}, DebugLevel, &ConsolePattern{Color: ColorAlways})
+	log.Error().String("k", "v").Msg("boom")
+
+	if !strings.Contains(buf.String(), "\x1b[31mERROR\x1b[0m") || !strings.Contains(buf.String(), colorDim+"k=v"+colorReset) {
+		t.Errorf("%q", buf.String())
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	var p = &ConsolePattern{}
+	p.detect(&Stdout{os.Stdout})
+	if p.isColored() {
+		t.Error("设置NO_COLOR时不应使用颜色")
+	}
+}
+
+func TestConsolePatternDetectWrapped(t *testing.T) {
+	//字符设备与终端的判断方式相同
+	tty, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil || !isTerminal(tty) {
+		t.Skip("没有可用的字符设备")
+	}
+	defer tty.Close()
+
+	//值为空的NO_COLOR不禁用颜色
+	t.Setenv("NO_COLOR", "")
+
+	var p = &ConsolePattern{}
+	var log = New(NewMultipleWriter(NewLevelFilter(&Stdout{tty}, InfoLevel, PanicLevel)), DebugLevel, p)
+	if !p.isColored() {
+		t.Error("包装后的终端应使用颜色")
+	}
+
+	//增加写入非终端的Writer后不再使用颜色
+	var buf bytes.Buffer
+	log.AddWriter(&Stdout{&buf})
+	if p.isColored() {
+		t.Error("同时写入非终端时不应使用颜色")
+	}
+
+	var async = &ConsolePattern{}
+	var log2 = New(NewAsyncWriter(&Stdout{tty}, 16, OverflowBlock), DebugLevel, async)
+	defer log2.Close()
+	if !async.isColored() {
+		t.Error("AsyncWriter包装的终端应使用颜色")
+	}
+}
@@ -0,0 +1,252 @@
+package onelog
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//RepeatedName 去重后汇总记录中重复次数的项名称
+var RepeatedName = "repeated"
+
+type fieldPos struct {
+	key   string
+	start int
+}
+
+type dedupeEntry struct {
+	level   Level
+	message string
+	//fields 参与去重的项在缓存中的内容，汇总记录中同样写入
+	fields   []byte
+	until    time.Time
+	repeated uint64
+}
+
+//dedupe 按等级、消息与指定的项去重，窗口内重复的记录不再写入，窗口结束时写入一条记录重复次数的汇总记录
+type dedupe struct {
+	window  time.Duration
+	fields  []string
+	entries map[string]*dedupeEntry
+	logger  *Logger
+	mutex   sync.Mutex
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+//dedupeSetting Logger当前的去重设置，由其所有的LevelWriter共用，记录时读取，设置时替换
+type dedupeSetting struct {
+	current atomic.Value
+	mutex   sync.Mutex
+}
+
+//load 返回当前的去重，未设置时为nil
+func (s *dedupeSetting) load() *dedupe {
+	if s == nil {
+		return nil
+	}
+
+	d, _ := s.current.Load().(*dedupe)
+	return d
+}
+
+//swap 替换当前的去重，返回原来的去重。每个去重只会被替换出一次，由取出方关闭
+func (s *dedupeSetting) swap(d *dedupe) *dedupe {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var old = s.load()
+	s.current.Store(d)
+
+	return old
+}
+
+//SetDedupe 设置去重。等级、消息以及fields指定的项都相同的记录，在window时间内只写入第一条，
+//窗口结束时写入一条RepeatedName项为重复次数的记录。window小于等于0时取消去重
+func (l *Logger) SetDedupe(window time.Duration, fields ...string) *Logger {
+	if l.base != nil {
+		l.base.SetDedupe(window, fields...)
+		return l
+	}
+
+	var d *dedupe
+	if window > 0 {
+		d = &dedupe{
+			window:  window,
+			fields:  fields,
+			entries: make(map[string]*dedupeEntry),
+			logger:  l,
+			stop:    make(chan struct{}),
+			done:    make(chan struct{}),
+		}
+		go d.run()
+	}
+
+	//先替换再关闭原来的去重，正在记录的日志使用的仍是原来的对象
+	if old := l.dedupe.swap(d); old != nil {
+		old.close()
+	}
+
+	return l
+}
+
+//key 生成去重使用的key
+func (d *dedupe) key(lw *DefaultLevelWriter, message string) string {
+	var buf = make([]byte, 0, 64+len(message))
+	buf = append(buf, byte(lw.level))
+	buf = append(buf, message...)
+
+	for _, field := range d.fields {
+		buf = append(buf, 0)
+		buf = append(buf, fieldBytes(lw, field)...)
+	}
+
+	return string(buf)
+}
+
+//keyFields 返回参与去重的项在缓存中的内容
+func (d *dedupe) keyFields(lw *DefaultLevelWriter) []byte {
+	var buf []byte
+	for _, field := range d.fields {
+		buf = append(buf, fieldBytes(lw, field)...)
+	}
+
+	return buf
+}
+
+//fieldBytes 返回一项在缓存中包括key与值的内容，没有此项时返回nil
+func fieldBytes(lw *DefaultLevelWriter, key string) []byte {
+	for i, f := range lw.fields {
+		if f.key != key {
+			continue
+		}
+		var end = len(lw.buffer)
+		if i+1 < len(lw.fields) {
+			end = lw.fields[i+1].start
+		}
+		return lw.buffer[f.start:end]
+	}
+
+	return nil
+}
+
+//suppress 返回此记录是否为窗口内的重复记录
+func (d *dedupe) suppress(lw *DefaultLevelWriter, message string) bool {
+	var key = d.key(lw, message)
+	var now = time.Now()
+
+	d.mutex.Lock()
+	var e, ok = d.entries[key]
+	if ok && now.Before(e.until) {
+		e.repeated++
+		d.mutex.Unlock()
+		lw.stats.addDropped(lw.level)
+		return true
+	}
+
+	d.entries[key] = &dedupeEntry{lw.level, message, d.keyFields(lw), now.Add(d.window), 0}
+	d.mutex.Unlock()
+
+	//上一个窗口还未汇总时先写入汇总记录
+	if ok && e.repeated > 0 {
+		d.logger.writeRepeated(e)
+	}
+
+	return false
+}
+
+//run 定时汇总已结束的窗口
+func (d *dedupe) run() {
+	defer close(d.done)
+
+	var ticker = time.NewTicker(d.window)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			d.sweep(false)
+		case <-d.stop:
+			d.sweep(true)
+			return
+		}
+	}
+}
+
+//sweep 删除已结束的窗口，有重复时写入汇总记录。all为true时处理所有的窗口
+func (d *dedupe) sweep(all bool) {
+	var now = time.Now()
+	var expired []*dedupeEntry
+
+	d.mutex.Lock()
+	for key, e := range d.entries {
+		if all || !now.Before(e.until) {
+			delete(d.entries, key)
+			if e.repeated > 0 {
+				expired = append(expired, e)
+			}
+		}
+	}
+	d.mutex.Unlock()
+
+	for _, e := range expired {
+		d.logger.writeRepeated(e)
+	}
+}
+
+func (d *dedupe) close() {
+	close(d.stop)
+	<-d.done
+}
+
+//writeRepeated 写入一条汇总记录，包括参与去重的项，此记录不参与去重
+func (l *Logger) writeRepeated(e *dedupeEntry) {
+	if summary, ok := l.summaryWriter(e.level).(*DefaultLevelWriter); ok {
+		summary.dedupe = nil
+		summary.buffer = append(summary.buffer, e.fields...)
+		summary.Uint64(RepeatedName, e.repeated).Msg(e.message)
+	}
+}
+
+//loadDedupe 按配置中的Dedupe设置Logger的去重
+func loadDedupe(log *Logger, config map[string]interface{}) error {
+	val, ok := config["Dedupe"]
+	if !ok {
+		return nil
+	}
+
+	conf, ok := val.(map[string]interface{})
+	if !ok {
+		return &MistakeType{"json type", "Dedupe"}
+	}
+
+	//以毫秒为单位
+	var window = 10 * time.Second
+	if val, ok := conf["Window"]; ok {
+		ms, ok := val.(float64)
+		if !ok || ms <= 0 {
+			return &MistakeType{"大于0的number type", "Window"}
+		}
+		window = time.Duration(ms) * time.Millisecond
+	}
+
+	var fields []string
+	if val, ok := conf["Fields"]; ok {
+		list, ok := val.([]interface{})
+		if !ok {
+			return &MistakeType{"[]string type", "Fields"}
+		}
+		for i, v := range list {
+			name, ok := v.(string)
+			if !ok {
+				return &MistakeType{"string type", "Fields:" + strconv.Itoa(i)}
+			}
+			fields = append(fields, name)
+		}
+	}
+
+	log.SetDedupe(window, fields...)
+
+	return nil
+}
@@ -0,0 +1,124 @@
+package onelog
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+//lockedBuffer 后台协程写入汇总记录时使用的线程安全的缓存
+type lockedBuffer struct {
+	bytes.Buffer
+	mutex sync.Mutex
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.Buffer.Write(p)
+}
+
+func TestDedupe(t *testing.T) {
+	var buf bytes.Buffer
+	var log = New(&Stdout{&buf}, DebugLevel, &JsonPattern{})
+	log.SetDedupe(time.Hour, "user")
+
+	for i := 0; i < 10; i++ {
+		log.Info().String("user", "a").Int("i", i).Msg("login failed")
+		log.Info().String("user", "b").Msg("login failed")
+	}
+	log.Warn().String("user", "a").Msg("login failed")
+
+	if n := strings.Count(buf.String(), "login failed"); n != 3 {
+		t.Errorf("窗口内应只写入3条，实际%d条:%s", n, buf.String())
+	}
+
+	buf.Reset()
+	log.Close()
+	if !strings.Contains(buf.String(), `"repeated":9`) || strings.Count(buf.String(), "repeated") != 2 {
+		t.Errorf("关闭时应写入重复次数:%s", buf.String())
+	}
+
+	//汇总记录中包括参与去重的项，不包括其他的项
+	for _, user := range []string{`"user":"a"`, `"user":"b"`} {
+		if !strings.Contains(buf.String(), user+`,"repeated":9`) {
+			t.Errorf("汇总记录中应包括%s:%s", user, buf.String())
+		}
+	}
+	if strings.Contains(buf.String(), `"i":`) {
+		t.Errorf("汇总记录中不应包括其他的项:%s", buf.String())
+	}
+}
+
+func TestDedupeMsgf(t *testing.T) {
+	var buf bytes.Buffer
+	var log = New(&Stdout{&buf}, DebugLevel, &JsonPattern{})
+	log.SetDedupe(time.Hour)
+
+	log.Info().Msgf("user %s failed %d", "a", 1)
+	log.Info().Msgf("user %s failed %d", "a", 1)
+	log.Info().Msgf("user %s failed %d", "b", 2)
+
+	if n := strings.Count(buf.String(), "failed"); n != 2 || !strings.Contains(buf.String(), "user b failed 2") {
+		t.Errorf("格式化后的消息不同时不应去重:%s", buf.String())
+	}
+	log.SetDedupe(0)
+}
+
+func TestDedupeWindow(t *testing.T) {
+	var buf lockedBuffer
+	var log = New(&Stdout{&buf}, DebugLevel, &JsonPattern{})
+	log.SetDedupe(20 * time.Millisecond)
+
+	log.Info().Msg("busy")
+	log.Info().Msg("busy")
+	log.Info().Msg("busy")
+	time.Sleep(60 * time.Millisecond)
+	log.Info().Msg("busy")
+	log.SetDedupe(0)
+
+	var lines = strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[1], `"repeated":2`) {
+		t.Errorf("窗口结束后应写入汇总记录:%s", buf.String())
+	}
+}
+
+func TestDedupeCloseTwice(t *testing.T) {
+	var log = New(&Stdout{&bytes.Buffer{}}, DebugLevel, &JsonPattern{})
+	log.SetDedupe(time.Second)
+
+	//WithCallerSkip生成的Logger同样关闭原Logger
+	log.Close()
+	log.WithCallerSkip(1).Close()
+	log.SetDedupe(time.Second)
+	log.SetDedupe(0)
+
+	if log.dedupe.load() != nil {
+		t.Error("取消后不应再去重")
+	}
+}
+
+func TestDedupeConfig(t *testing.T) {
+	var log = New(&Stdout{&bytes.Buffer{}}, TraceLevel, &JsonPattern{})
+	defer log.Close()
+
+	err := loadDedupe(log, map[string]interface{}{
+		"Dedupe": map[string]interface{}{"Window": float64(500), "Fields": []interface{}{"user", "ip"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := log.dedupe.load(); d.window != 500*time.Millisecond || len(d.fields) != 2 {
+		t.Errorf("%#v", d)
+	}
+	if log.lws[InfoLevel].(*DefaultLevelWriter).dedupe != log.dedupe {
+		t.Error("LevelWriter未设置去重")
+	}
+
+	if err := loadDedupe(log, map[string]interface{}{"Dedupe": map[string]interface{}{"Window": "1s"}}); err == nil {
+		t.Error("错误的Window应返回错误")
+	}
+}
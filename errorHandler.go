@@ -0,0 +1,158 @@
+package onelog
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//WriterError Writer在写入、切分、压缩、发送等操作中出现的错误
+type WriterError struct {
+	//Writer 出错的Writer名称，如FileWriter
+	Writer string
+	//Op 出错的操作，如write、rename、compress、send
+	Op string
+	//Path 相关的文件或地址，可为空
+	Path string
+	Err  error
+}
+
+func (e *WriterError) Error() string {
+	var b strings.Builder
+	b.WriteString(e.Writer)
+	b.WriteByte(' ')
+	b.WriteString(e.Op)
+	if e.Path != "" {
+		b.WriteByte(' ')
+		b.WriteString(e.Path)
+	}
+	b.WriteString(":")
+	b.WriteString(e.Err.Error())
+
+	return b.String()
+}
+
+func (e *WriterError) Unwrap() error {
+	return e.Err
+}
+
+//ErrorHandler 处理Writer出现的错误。可能在后台协程中被调用，不能在其中使用出错的Logger写日志
+type ErrorHandler func(err *WriterError)
+
+var (
+	globalErrorHandler atomic.Value
+	//errorOutput 未设置错误处理时的输出
+	errorOutput   io.Writer = os.Stderr
+	errorInterval           = time.Second
+	fallback      struct {
+		last       time.Time
+		suppressed int
+		mutex      sync.Mutex
+	}
+)
+
+//SetErrorHandler 设置全局的错误处理，未单独设置错误处理的Logger与Writer出现错误时调用。
+//handler为nil时错误将输出至stderr，每秒最多输出一条
+func SetErrorHandler(handler ErrorHandler) {
+	globalErrorHandler.Store(handler)
+}
+
+//reportError 交给全局的错误处理，未设置时以限制频率的方式输出至stderr
+func reportError(e *WriterError) {
+	if h, _ := globalErrorHandler.Load().(ErrorHandler); h != nil {
+		h(e)
+		return
+	}
+
+	fallback.mutex.Lock()
+	defer fallback.mutex.Unlock()
+
+	var now = time.Now()
+	if now.Sub(fallback.last) < errorInterval {
+		fallback.suppressed++
+		return
+	}
+	fallback.last = now
+
+	if fallback.suppressed > 0 {
+		_, _ = fmt.Fprintf(errorOutput, "onelog: %v (另有%d条错误未输出)\n", e, fallback.suppressed)
+		fallback.suppressed = 0
+	} else {
+		_, _ = fmt.Fprintf(errorOutput, "onelog: %v\n", e)
+	}
+}
+
+//errorReporter 保存单独设置的错误处理，嵌入至需要报告错误的Writer中
+type errorReporter struct {
+	handler atomic.Value
+	count   uint64
+}
+
+//failures 返回已报告的错误数
+func (r *errorReporter) failures() uint64 {
+	if r == nil {
+		return 0
+	}
+
+	return atomic.LoadUint64(&r.count)
+}
+
+//SetErrorHandler 单独设置此对象的错误处理，handler为nil时使用全局的错误处理
+func (r *errorReporter) SetErrorHandler(handler ErrorHandler) {
+	r.handler.Store(handler)
+}
+
+//report 报告一个错误，err为nil时不做处理
+func (r *errorReporter) report(writer, op, path string, err error) {
+	if err == nil {
+		return
+	}
+
+	//暂停使用的原因已在暂停时报告过
+	if _, ok := err.(Benched); ok {
+		return
+	}
+
+	//写入多个Writer时分别报告每一个错误
+	if errs, ok := err.(WriteErrors); ok {
+		for _, e := range errs {
+			r.report(writer, op, path, e)
+		}
+		return
+	}
+
+	e, ok := err.(*WriterError)
+	if !ok {
+		e = &WriterError{writer, op, path, err}
+	}
+
+	if r != nil {
+		atomic.AddUint64(&r.count, 1)
+		if h, _ := r.handler.Load().(ErrorHandler); h != nil {
+			h(e)
+			return
+		}
+	}
+
+	reportError(e)
+}
+
+//writerName 返回Writer的类型名称，用于错误信息
+func writerName(writer Writer) string {
+	var name = fmt.Sprintf("%T", writer)
+
+	return name[strings.LastIndexByte(name, '.')+1:]
+}
+
+//setErrorHandler 为writer以及其包装的所有Writer设置错误处理
+func setErrorHandler(writer Writer, handler ErrorHandler) {
+	eachWriter(writer, func(w Writer) {
+		if r, ok := w.(interface{ SetErrorHandler(ErrorHandler) }); ok {
+			r.SetErrorHandler(handler)
+		}
+	})
+}
@@ -0,0 +1,70 @@
+package onelog
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+//failWriter 每次写入都返回错误的Writer
+type failWriter struct {
+	Stdout
+}
+
+func (*failWriter) Write(p []byte) (n int, err error) {
+	return 0, errors.New("disk full")
+}
+
+func TestLoggerErrorHandler(t *testing.T) {
+	var got []*WriterError
+	var log = New(&failWriter{}, InfoLevel, &JsonPattern{})
+	log.SetErrorHandler(func(err *WriterError) {
+		got = append(got, err)
+	})
+
+	log.Info().Msg("lost")
+	if len(got) != 1 || got[0].Writer != "failWriter" || got[0].Op != "write" || got[0].Err.Error() != "disk full" {
+		t.Fatalf("%v", got)
+	}
+
+	//关闭后写入的错误，多个Writer的错误分别报告
+	fw, _ := NewFileWriter(filepath.Join(t.TempDir(), "e.log"), 1024)
+	log.AddWriter(fw)
+	fw.Close()
+	got = nil
+	log.Info().Msg("closed")
+	if len(got) != 2 || got[0].Writer != "FileWriter" || got[1].Writer != "failWriter" {
+		t.Fatalf("%v", got)
+	}
+	if _, ok := errors.Unwrap(got[0]).(Closed); !ok {
+		t.Errorf("%v", got[0])
+	}
+}
+
+func TestErrorFallbackRateLimit(t *testing.T) {
+	var buf bytes.Buffer
+	var output, interval = errorOutput, errorInterval
+	errorOutput, errorInterval = &buf, 50*time.Millisecond
+	defer func() {
+		errorOutput, errorInterval = output, interval
+	}()
+	fallback.last, fallback.suppressed = time.Time{}, 0
+
+	var e = &WriterError{"FileWriter", "write", "a.log", errors.New("disk full")}
+	for i := 0; i < 10; i++ {
+		reportError(e)
+	}
+	time.Sleep(60 * time.Millisecond)
+	reportError(e)
+
+	var lines = strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[1], "另有9条") {
+		t.Errorf("%q", lines)
+	}
+	if lines[0] != "onelog: FileWriter write a.log:disk full" {
+		t.Error(lines[0])
+	}
+}
@@ -0,0 +1,230 @@
+package onelog
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//WriteErrors 写入多个Writer时出现的所有错误
+type WriteErrors []error
+
+func (e WriteErrors) Error() string {
+	var msg = make([]string, len(e))
+	for i, err := range e {
+		msg[i] = err.Error()
+	}
+
+	return strings.Join(msg, "; ")
+}
+
+func (e WriteErrors) Unwrap() []error {
+	return e
+}
+
+//Benched Writer因连续失败而暂停使用
+type Benched string
+
+func (e Benched) Error() string {
+	return string(e) + "暂停使用中"
+}
+
+//AllBenched 所有的Writer都暂停使用中，记录未写入任何Writer
+type AllBenched string
+
+func (e AllBenched) Error() string {
+	return string(e) + "中所有的Writer暂停使用中，记录未写入"
+}
+
+//HealthWriter 连续失败达到指定次数后暂停使用被包装的Writer，暂停期间的写入直接返回Benched。
+//暂停时间过后的第一次写入作为探测，成功后恢复使用，失败则继续暂停。同一时间只有一个探测，探测期间的其他写入仍返回Benched
+type HealthWriter struct {
+	Writer    Writer
+	threshold int
+	benchTime time.Duration
+	failures  int
+	until     time.Time
+	//probing 为1时有一个写入正在探测
+	probing uint32
+	mutex   sync.Mutex
+	errorReporter
+}
+
+//NewHealthWriter 返回一个新的HealthWriter，连续失败failures次后暂停benchTime
+func NewHealthWriter(writer Writer, failures int, benchTime time.Duration) *HealthWriter {
+	if failures <= 0 {
+		failures = 1
+	}
+
+	return &HealthWriter{Writer: writer, threshold: failures, benchTime: benchTime}
+}
+
+//Healthy 返回被包装的Writer当前是否可用
+func (h *HealthWriter) Healthy() bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return h.failures < h.threshold
+}
+
+func (h *HealthWriter) Write(p []byte) (n int, err error) {
+	return h.WriteLevel(Disable, p)
+}
+
+func (h *HealthWriter) WriteLevel(level Level, p []byte) (n int, err error) {
+	h.mutex.Lock()
+	var benched = h.failures >= h.threshold
+	if benched && time.Now().Before(h.until) {
+		h.mutex.Unlock()
+		return 0, Benched(writerName(h.Writer))
+	}
+	h.mutex.Unlock()
+
+	//暂停时间已过，只有一个写入作为探测
+	if benched {
+		if !atomic.CompareAndSwapUint32(&h.probing, 0, 1) {
+			return 0, Benched(writerName(h.Writer))
+		}
+		defer atomic.StoreUint32(&h.probing, 0)
+	}
+
+	n, err = writeLevel(h.Writer, level, p)
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if err == nil {
+		h.failures = 0
+		return
+	}
+
+	h.failures++
+	if h.failures >= h.threshold {
+		//第一次暂停时报告原因，之后的探测失败不再报告
+		if h.failures == h.threshold {
+			h.report(writerName(h.Writer), "bench", "", err)
+		}
+		h.until = time.Now().Add(h.benchTime)
+	}
+
+	return
+}
+
+func (h *HealthWriter) Close() {
+	h.Writer.Close()
+}
+
+func (h *HealthWriter) Flush() {
+	flush(h.Writer)
+}
+
+func (h *HealthWriter) flushSoon() {
+	flushSoon(h.Writer)
+}
+
+//SetConfig HealthWriter只能在其他Writer的配置中通过BenchAfter与BenchTime生成
+func (h *HealthWriter) SetConfig(config interface{}) error {
+	return NotUnderstand("HealthWriter:WriterPara")
+}
+
+//FailoverWriter 依次尝试写入每一个Writer，直到成功为止。第一个为主Writer，其后为备用的Writer
+type FailoverWriter struct {
+	Writers []Writer
+}
+
+//NewFailoverWriter 返回一个新的FailoverWriter，primary失败时写入fallbacks
+func NewFailoverWriter(primary Writer, fallbacks ...Writer) *FailoverWriter {
+	return &FailoverWriter{append([]Writer{primary}, fallbacks...)}
+}
+
+func (f *FailoverWriter) Write(p []byte) (n int, err error) {
+	return f.WriteLevel(Disable, p)
+}
+
+//WriteLevel 写入第一个可用的Writer，全部失败时返回所有的错误
+func (f *FailoverWriter) WriteLevel(level Level, p []byte) (n int, err error) {
+	var errs WriteErrors
+	for _, w := range f.Writers {
+		if n, err = writeLevel(w, level, p); err == nil {
+			return
+		}
+		errs = append(errs, namedError(w, err))
+	}
+
+	return 0, errs
+}
+
+func (f *FailoverWriter) Close() {
+	for _, w := range f.Writers {
+		w.Close()
+	}
+}
+
+func (f *FailoverWriter) Flush() {
+	for _, w := range f.Writers {
+		flush(w)
+	}
+}
+
+func (f *FailoverWriter) flushSoon() {
+	for _, w := range f.Writers {
+		flushSoon(w)
+	}
+}
+
+//SetConfig FailoverWriter只能在其他Writer的配置中通过Fallback生成
+func (f *FailoverWriter) SetConfig(config interface{}) error {
+	return NotUnderstand("FailoverWriter:WriterPara")
+}
+
+//namedError 为Writer返回的错误加上Writer的名称
+func namedError(writer Writer, err error) error {
+	switch err.(type) {
+	case *WriterError, WriteErrors, Benched:
+		return err
+	}
+
+	return &WriterError{writerName(writer), "write", "", err}
+}
+
+//faultTolerance 按配置中的BenchAfter、BenchTime与Fallback包装writer
+func faultTolerance(writer Writer, config map[string]interface{}) (Writer, error) {
+	if val, ok := config["BenchAfter"]; ok {
+		failures, ok := val.(float64)
+		if !ok {
+			return nil, &MistakeType{"number type", "BenchAfter"}
+		}
+		if failures <= 0 {
+			return nil, &MistakeType{"大于0", strconv.Itoa(int(failures))}
+		}
+
+		//以毫秒为单位
+		var benchTime = 30 * time.Second
+		if val, ok := config["BenchTime"]; ok {
+			ms, ok := val.(float64)
+			if !ok || ms <= 0 {
+				return nil, &MistakeType{"大于0的number type", "BenchTime"}
+			}
+			benchTime = time.Duration(ms) * time.Millisecond
+		}
+
+		writer = NewHealthWriter(writer, int(failures), benchTime)
+	}
+
+	if val, ok := config["Fallback"]; ok {
+		rec, ok := val.(map[string]interface{})
+		if !ok {
+			return nil, &MistakeType{"json type", "Fallback"}
+		}
+
+		fallback, err := newWriterFromConfig(rec)
+		if err != nil {
+			return nil, err
+		}
+		writer = NewFailoverWriter(writer, fallback)
+	}
+
+	return writer, nil
+}
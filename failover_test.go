@@ -0,0 +1,158 @@
+package onelog
+
+import (
+	"bytes"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+//toggleWriter 可控制是否失败的Writer
+type toggleWriter struct {
+	Stdout
+	fail   bool
+	writes int
+}
+
+func (t *toggleWriter) Write(p []byte) (n int, err error) {
+	t.writes++
+	if t.fail {
+		return 0, errors.New("broken")
+	}
+
+	return t.Stdout.Write(p)
+}
+
+func TestMultipleWriterContinueOnError(t *testing.T) {
+	var buf bytes.Buffer
+	var broken = &toggleWriter{fail: true}
+	var m = NewMultipleWriter(&Stdout{&buf}, broken)
+
+	n, err := m.Write([]byte("a"))
+	if n != 1 || buf.String() != "a" {
+		t.Errorf("失败的Writer之后的Writer也应写入:%d %q", n, buf.String())
+	}
+	if errs, ok := err.(WriteErrors); !ok || len(errs) != 1 {
+		t.Errorf("%v", err)
+	}
+}
+
+func TestMultipleWriterAllBenched(t *testing.T) {
+	var a = NewHealthWriter(&toggleWriter{fail: true}, 1, time.Hour)
+	var b = NewHealthWriter(&toggleWriter{fail: true}, 1, time.Hour)
+	var m = NewMultipleWriter(a, b)
+	_, _ = m.Write([]byte("a"))
+
+	var log = New(m, InfoLevel, &JsonPattern{})
+	log.Info().Msg("lost")
+
+	//两个Writer都暂停使用，记录未写入任何Writer
+	n, err := m.Write([]byte("b"))
+	if n != 0 || !errors.As(err, new(AllBenched)) {
+		t.Errorf("所有Writer暂停时应返回AllBenched:%d %v", n, err)
+	}
+	if log.Stats().Errors != 1 {
+		t.Errorf("未写入的记录应计入错误:%d", log.Stats().Errors)
+	}
+}
+
+func TestFailoverAndBench(t *testing.T) {
+	var buf bytes.Buffer
+	var primary = &toggleWriter{Stdout: Stdout{&buf}, fail: true}
+	var backup bytes.Buffer
+	var health = NewHealthWriter(primary, 2, 30*time.Millisecond)
+	var f = NewFailoverWriter(health, &Stdout{&backup})
+
+	for i := 0; i < 5; i++ {
+		if _, err := f.Write([]byte("x")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if backup.Len() != 5 {
+		t.Errorf("备用Writer应收到全部记录:%d", backup.Len())
+	}
+	//失败2次后暂停，不再调用主Writer
+	if primary.writes != 2 || health.Healthy() {
+		t.Errorf("主Writer应被暂停:%d", primary.writes)
+	}
+
+	//暂停结束后探测成功，恢复使用
+	primary.fail = false
+	time.Sleep(40 * time.Millisecond)
+	_, _ = f.Write([]byte("y"))
+	if buf.String() != "y" || !health.Healthy() {
+		t.Errorf("探测后应恢复使用:%q", buf.String())
+	}
+}
+
+//probeWriter 写入时等待release，用于模拟正在进行的探测
+type probeWriter struct {
+	Stdout
+	release chan struct{}
+	writes  int32
+}
+
+func (s *probeWriter) Write(p []byte) (n int, err error) {
+	atomic.AddInt32(&s.writes, 1)
+	<-s.release
+	return len(p), nil
+}
+
+func TestHealthWriterSingleProbe(t *testing.T) {
+	var slow = &probeWriter{release: make(chan struct{})}
+	var health = NewHealthWriter(slow, 1, time.Millisecond)
+	health.failures, health.until = 1, time.Now()
+
+	var done = make(chan struct{})
+	go func() {
+		_, _ = health.Write([]byte("probe"))
+		close(done)
+	}()
+	for atomic.LoadInt32(&slow.writes) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	//探测进行中，其他写入不再调用被包装的Writer
+	for i := 0; i < 10; i++ {
+		if _, err := health.Write([]byte("x")); !errors.As(err, new(Benched)) {
+			t.Errorf("探测期间应返回Benched:%v", err)
+		}
+	}
+	close(slow.release)
+	<-done
+
+	if n := atomic.LoadInt32(&slow.writes); n != 1 || !health.Healthy() {
+		t.Errorf("应只探测一次:%d", n)
+	}
+	if _, err := health.Write([]byte("y")); err != nil || atomic.LoadInt32(&slow.writes) != 2 {
+		t.Errorf("探测成功后应恢复使用:%v", err)
+	}
+}
+
+func TestFaultToleranceConfig(t *testing.T) {
+	var m = &MultipleWriter{}
+	err := m.SetConfig([]interface{}{
+		map[string]interface{}{
+			"Writer":     "console",
+			"WriterPara": map[string]interface{}{"Console": "Stdout"},
+			"BenchAfter": float64(3),
+			"BenchTime":  float64(1000),
+			"Fallback": map[string]interface{}{
+				"Writer":     "console",
+				"WriterPara": map[string]interface{}{"Console": "Stderr"},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, ok := m.Writer.(*FailoverWriter)
+	if !ok || len(f.Writers) != 2 {
+		t.Fatalf("%T", m.Writer)
+	}
+	if h, ok := f.Writers[0].(*HealthWriter); !ok || h.threshold != 3 || h.benchTime != time.Second {
+		t.Errorf("%#v", f.Writers[0])
+	}
+}
@@ -0,0 +1,23 @@
+//go:build !windows
+// +build !windows
+
+package onelog
+
+import (
+	"os"
+	"syscall"
+)
+
+//lockFile 对文件加排他的建议锁，已被其他进程持有时等待
+func lockFile(f *os.File) error {
+	for {
+		err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX)
+		if err != syscall.EINTR {
+			return err
+		}
+	}
+}
+
+func unlockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
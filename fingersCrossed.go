@@ -0,0 +1,191 @@
+package onelog
+
+import "sync"
+
+//crossedWriter 先将记录缓存在内存中，写入等于或高于trigger等级的记录后，将缓存的记录与之后的记录全部写入Writer
+type crossedWriter struct {
+	writer     Writer
+	trigger    Level
+	maxRecords int
+	records    []ringRecord
+	triggered  bool
+	released   bool
+	mutex      sync.Mutex
+}
+
+func (c *crossedWriter) Write(p []byte) (n int, err error) {
+	return c.WriteLevel(Disable, p)
+}
+
+//WriteLevel 未触发时缓存记录，超过最大条数时丢弃最早的记录。未知等级的记录(直接调用Write)只缓存不触发
+func (c *crossedWriter) WriteLevel(level Level, p []byte) (n int, err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	switch {
+	case c.released:
+		return len(p), nil
+	case c.triggered:
+		return writeLevel(c.writer, level, p)
+	case level != Disable && level >= c.trigger:
+		if err := c.flushRecords(); err != nil {
+			return 0, err
+		}
+		return writeLevel(c.writer, level, p)
+	}
+
+	if c.maxRecords > 0 && len(c.records) >= c.maxRecords {
+		copy(c.records, c.records[1:])
+		c.records = c.records[:len(c.records)-1]
+	}
+	c.records = append(c.records, ringRecord{level, append([]byte(nil), p...)})
+
+	return len(p), nil
+}
+
+//flushRecords 进入触发状态，将缓存的记录写入Writer
+func (c *crossedWriter) flushRecords() error {
+	c.triggered = true
+
+	var errs WriteErrors
+	for _, r := range c.records {
+		if _, err := writeLevel(c.writer, r.level, r.data); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	c.records = nil
+
+	if errs != nil {
+		return errs
+	}
+
+	return nil
+}
+
+//Close 丢弃缓存的记录，不关闭其写入的Writer
+func (c *crossedWriter) Close() {
+	c.mutex.Lock()
+	c.released = true
+	c.records = nil
+	c.mutex.Unlock()
+}
+
+func (c *crossedWriter) Flush() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.triggered {
+		flush(c.writer)
+	}
+}
+
+func (c *crossedWriter) flushSoon() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.triggered {
+		flushSoon(c.writer)
+	}
+}
+
+//SetConfig 只能由Logger.FingersCrossed生成，不能从配置文件中使用
+func (c *crossedWriter) SetConfig(config interface{}) error {
+	return NotUnderstand("crossedWriter")
+}
+
+//ScopedLogger 由Logger.FingersCrossed生成的日志对象，用于一次请求等范围内。
+//范围内的记录先缓存在内存中，出现等于或高于触发等级的记录时才全部写入，否则在Release时丢弃
+type ScopedLogger struct {
+	*Logger
+	crossed *crossedWriter
+}
+
+//FingersCrossed 返回一个在范围内缓存记录的日志对象，记录等于或高于level的记录，带有此Logger的通用项并写入此Logger的Writer。
+//出现等于或高于trigger等级的记录时，将缓存的记录与之后的记录全部写入。maxRecords为最多缓存的条数，超过时丢弃最早的记录，
+//小于等于0时不限制。范围结束时需要调用Release
+func (l *Logger) FingersCrossed(level, trigger Level, maxRecords int) *ScopedLogger {
+	if l.base != nil {
+		return l.base.FingersCrossed(level, trigger, maxRecords)
+	}
+
+	var crossed = &crossedWriter{
+		writer:     l.writer,
+		trigger:    trigger,
+		maxRecords: maxRecords,
+	}
+
+	var scoped = &Logger{
+		lws:      make([]LevelWriter, 8),
+		writer:   crossed,
+		minLevel: level,
+		pattern:  l.pattern,
+		errors:   l.errors,
+		sampling: &sampling{},
+		dedupe:   &dedupeSetting{},
+		stats:    l.stats,
+	}
+
+	//此Logger中未启用的等级，使用已启用等级的通用项
+	var ref *DefaultLevelWriter
+	for i := TraceLevel; i <= PanicLevel && ref == nil; i++ {
+		ref, _ = l.lws[i].(*DefaultLevelWriter)
+	}
+
+	for i := TraceLevel; i <= PanicLevel; i++ {
+		if i < level {
+			scoped.lws[i] = disableLevelWriter
+			continue
+		}
+
+		var lw, ok = l.lws[i].(*DefaultLevelWriter)
+		if ok {
+			lw = lw.clone().(*DefaultLevelWriter)
+			lw.origin = nil
+		} else {
+			lw = newDefaultLevelWriter(crossed, i, l.pattern)
+			lw.errors = l.errors
+			lw.stats = l.stats
+			if ref != nil {
+				var base = newDefaultLevelWriter(crossed, ref.level, l.pattern)
+				lw.buffer = append(lw.buffer, ref.buffer[len(base.buffer):]...)
+				lw.runtimeComputes = ref.runtimeComputes
+			}
+		}
+		lw.Writer = crossed
+		lw.dedupe = scoped.dedupe
+		scoped.lws[i] = lw
+	}
+
+	return &ScopedLogger{scoped, crossed}
+}
+
+//Trigger 立即将缓存的记录写入，之后的记录也将直接写入
+func (s *ScopedLogger) Trigger() {
+	s.crossed.mutex.Lock()
+	defer s.crossed.mutex.Unlock()
+
+	if s.crossed.released || s.crossed.triggered {
+		return
+	}
+	if err := s.crossed.flushRecords(); err != nil {
+		s.errors.report(writerName(s.crossed.writer), "write", "", err)
+	}
+}
+
+//Triggered 返回是否已经触发
+func (s *ScopedLogger) Triggered() bool {
+	s.crossed.mutex.Lock()
+	defer s.crossed.mutex.Unlock()
+
+	return s.crossed.triggered
+}
+
+//Release 结束此范围，未触发时丢弃缓存的记录，之后的记录也将被丢弃。不会关闭原Logger的Writer
+func (s *ScopedLogger) Release() {
+	s.crossed.Close()
+}
+
+//Close 与Release相同
+func (s *ScopedLogger) Close() {
+	s.Release()
+}
@@ -0,0 +1,64 @@
+package onelog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestFingersCrossed(t *testing.T) {
+	var buf bytes.Buffer
+	var log = New(&Stdout{&buf}, InfoLevel, &JsonPattern{})
+	log.AddStatic("app", "test")
+
+	var scope = log.FingersCrossed(DebugLevel, ErrorLevel, 0)
+	scope.Debug().Msg("step 1")
+	scope.Info().Msg("step 2")
+	scope.Trace().Msg("trace")
+	if buf.Len() != 0 || scope.Triggered() {
+		t.Fatalf("未触发时不应写入:%s", buf.String())
+	}
+
+	scope.Error().Msg("failed")
+	scope.Debug().Msg("after")
+	scope.Release()
+	scope.Debug().Msg("released")
+
+	var lines = strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 || !strings.Contains(lines[0], "step 1") || !strings.Contains(lines[2], "failed") || !strings.Contains(lines[3], "after") {
+		t.Fatalf("触发后应按顺序写入全部记录:%s", buf.String())
+	}
+	if !strings.Contains(lines[0], `"app":"test"`) {
+		t.Errorf("应保留Logger的通用项:%s", lines[0])
+	}
+
+	buf.Reset()
+	scope = log.FingersCrossed(DebugLevel, ErrorLevel, 0)
+	scope.Debug().Msg("ok")
+	scope.Warn().Msg("slow")
+	scope.Release()
+	if buf.Len() != 0 {
+		t.Errorf("未触发时应丢弃:%s", buf.String())
+	}
+
+	log.Info().Msg("parent")
+	if !strings.Contains(buf.String(), "parent") {
+		t.Error("Release不应关闭原Logger")
+	}
+}
+
+func TestFingersCrossedMaxRecords(t *testing.T) {
+	var buf bytes.Buffer
+	var log = New(&Stdout{&buf}, InfoLevel, &JsonPattern{})
+
+	var scope = log.FingersCrossed(DebugLevel, WarnLevel, 3)
+	for _, msg := range []string{"a", "b", "c", "d", "e"} {
+		scope.Debug().Msg(msg)
+	}
+	scope.Trigger()
+	scope.Release()
+
+	if strings.Contains(buf.String(), `"msg":"b"`) || !strings.Contains(buf.String(), `"msg":"c"`) || strings.Count(buf.String(), "\n") != 3 {
+		t.Errorf("应只保留最近的3条:%s", buf.String())
+	}
+}
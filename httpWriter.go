@@ -0,0 +1,522 @@
+package onelog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//maxRetryWait 重试前最长的等待时间，服务端给出更长的Retry-After时也以此为准
+const maxRetryWait = 30 * time.Second
+
+//HTTPWriter 将记录按批以NDJSON的格式POST至收集服务的Writer。达到条数、字节数或最长等待时间时发送一批，
+//服务端返回5xx或429时将以退避的方式重试。发送跟不上时等待发送的批次已满，新的一批将被丢弃并计入失败
+type HTTPWriter struct {
+	url        string
+	client     *http.Client
+	header     http.Header
+	gzip       bool
+	maxCount   int
+	maxBytes   int
+	maxLatency time.Duration
+	maxRetries int
+	batch      []byte
+	count      int
+	first      time.Time
+	sent       uint64
+	failed     uint64
+	closed     bool
+	batches    chan httpBatch
+	sending    sync.WaitGroup
+	flushes    chan chan struct{}
+	soon       chan struct{}
+	stop       chan struct{}
+	done       chan struct{}
+	mutex      sync.Mutex
+	errorReporter
+	writerCounters
+}
+
+type httpBatch struct {
+	body  []byte
+	count int
+}
+
+//NewHTTPWriter 返回一个新的HTTPWriter，默认每批最多500条、1M，最长等待1秒
+func NewHTTPWriter(url string) *HTTPWriter {
+	var w = &HTTPWriter{}
+	w.init(url)
+
+	return w
+}
+
+func (w *HTTPWriter) init(url string) {
+	w.url = url
+	w.client = &http.Client{Timeout: 10 * time.Second}
+	w.header = make(http.Header)
+	w.header.Set("Content-Type", "application/x-ndjson")
+	w.maxCount = 500
+	w.maxBytes = 1024 * 1024
+	w.maxLatency = time.Second
+	w.maxRetries = 3
+	w.batches = make(chan httpBatch, 4)
+	w.flushes = make(chan chan struct{})
+	w.soon = make(chan struct{}, 1)
+	w.stop = make(chan struct{})
+	w.done = make(chan struct{})
+
+	go w.run()
+}
+
+//SetBatch 设置每批最多的条数、字节数与最长的等待时间，值小于等于0的项不做修改
+func (w *HTTPWriter) SetBatch(count, size int, latency time.Duration) *HTTPWriter {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if count > 0 {
+		w.maxCount = count
+	}
+	if size > 0 {
+		w.maxBytes = size
+	}
+	if latency > 0 {
+		w.maxLatency = latency
+	}
+
+	return w
+}
+
+//SetHeader 设置请求的头信息
+func (w *HTTPWriter) SetHeader(key, value string) *HTTPWriter {
+	w.mutex.Lock()
+	w.header.Set(key, value)
+	w.mutex.Unlock()
+
+	return w
+}
+
+//SetBasicAuth 设置请求使用的用户名与密码
+func (w *HTTPWriter) SetBasicAuth(username, password string) *HTTPWriter {
+	var r = &http.Request{Header: make(http.Header)}
+	r.SetBasicAuth(username, password)
+
+	return w.SetHeader("Authorization", r.Header.Get("Authorization"))
+}
+
+//SetGzip 设置是否对请求内容进行gzip压缩
+func (w *HTTPWriter) SetGzip(enable bool) *HTTPWriter {
+	w.mutex.Lock()
+	w.gzip = enable
+	w.mutex.Unlock()
+
+	return w
+}
+
+//SetMaxRetries 设置发送失败时最多的重试次数
+func (w *HTTPWriter) SetMaxRetries(retries int) *HTTPWriter {
+	w.mutex.Lock()
+	w.maxRetries = retries
+	w.mutex.Unlock()
+
+	return w
+}
+
+//SetClient 设置发送使用的http.Client
+func (w *HTTPWriter) SetClient(client *http.Client) *HTTPWriter {
+	w.mutex.Lock()
+	w.client = client
+	w.mutex.Unlock()
+
+	return w
+}
+
+//Sent 返回已成功发送的记录条数
+func (w *HTTPWriter) Sent() uint64 {
+	return atomic.LoadUint64(&w.sent)
+}
+
+//Failed 返回重试后仍发送失败而被丢弃的记录条数
+func (w *HTTPWriter) Failed() uint64 {
+	return atomic.LoadUint64(&w.failed)
+}
+
+func (w *HTTPWriter) Write(p []byte) (n int, err error) {
+	w.mutex.Lock()
+
+	if w.closed {
+		w.mutex.Unlock()
+		return 0, Closed("HTTPWriter")
+	}
+
+	if w.count == 0 {
+		w.first = time.Now()
+	}
+	w.batch = append(w.batch, p...)
+	w.addWritten(len(p))
+	if len(p) == 0 || p[len(p)-1] != '\n' {
+		w.batch = append(w.batch, '\n')
+	}
+	w.count++
+
+	if w.count < w.maxCount && len(w.batch) < w.maxBytes {
+		w.mutex.Unlock()
+		return len(p), nil
+	}
+
+	var b = w.cut()
+	//与closed在同一次加锁中登记，停止时run会等待所有登记的批次交出后才退出
+	w.sending.Add(1)
+	w.mutex.Unlock()
+	defer w.sending.Done()
+
+	//发送跟不上时不等待，丢弃这一批
+	select {
+	case w.batches <- b:
+	default:
+		atomic.AddUint64(&w.failed, uint64(b.count))
+		w.report("HTTPWriter", "drop", w.url, NotNil("等待发送的空位"))
+	}
+
+	return len(p), nil
+}
+
+//cut 取出当前的一批，调用方需要持有锁
+func (w *HTTPWriter) cut() httpBatch {
+	var b = httpBatch{w.batch, w.count}
+	w.batch = make([]byte, 0, len(b.body))
+	w.count = 0
+
+	return b
+}
+
+//run 后台发送的协程
+func (w *HTTPWriter) run() {
+	defer close(w.done)
+
+	var interval = w.interval()
+	var ticker = time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case b := <-w.batches:
+			w.send(b)
+		case <-ticker.C:
+			w.mutex.Lock()
+			if w.count > 0 && time.Since(w.first) >= w.maxLatency {
+				var b = w.cut()
+				w.mutex.Unlock()
+				w.send(b)
+			} else {
+				w.mutex.Unlock()
+			}
+
+			//SetBatch修改了等待时间时调整检查的间隔
+			if i := w.interval(); i != interval {
+				interval = i
+				ticker.Reset(interval)
+			}
+		case done := <-w.flushes:
+			w.drain()
+			close(done)
+		case <-w.soon:
+			w.drain()
+		case <-w.stop:
+			w.stopping()
+			return
+		}
+	}
+}
+
+//stopping 接收停止前已取出但还未交出的批次，全部交出后发送剩余的记录
+func (w *HTTPWriter) stopping() {
+	var handed = make(chan struct{})
+	go func() {
+		w.sending.Wait()
+		close(handed)
+	}()
+
+	for {
+		select {
+		case b := <-w.batches:
+			w.send(b)
+		case <-handed:
+			w.drain()
+			return
+		}
+	}
+}
+
+//interval 检查当前一批是否已超过最长等待时间的间隔
+func (w *HTTPWriter) interval() time.Duration {
+	w.mutex.Lock()
+	var interval = w.maxLatency / 4
+	w.mutex.Unlock()
+
+	if interval < 10*time.Millisecond {
+		interval = 10 * time.Millisecond
+	}
+
+	return interval
+}
+
+//drain 发送所有已在等待的批次与当前未满的一批
+func (w *HTTPWriter) drain() {
+	for {
+		select {
+		case b := <-w.batches:
+			w.send(b)
+			continue
+		default:
+		}
+		break
+	}
+
+	w.mutex.Lock()
+	var b = w.cut()
+	w.mutex.Unlock()
+
+	if b.count > 0 {
+		w.send(b)
+	}
+}
+
+//send 发送一批记录，失败时以退避的方式重试。停止后不再等待重试
+func (w *HTTPWriter) send(b httpBatch) {
+	w.mutex.Lock()
+	var client = w.client
+	var header = w.header.Clone()
+	var useGzip = w.gzip
+	var maxRetries = w.maxRetries
+	w.mutex.Unlock()
+
+	var body = b.body
+	if useGzip {
+		var buf bytes.Buffer
+		gw := gzip.NewWriter(&buf)
+		_, _ = gw.Write(body)
+		_ = gw.Close()
+		body = buf.Bytes()
+		header.Set("Content-Encoding", "gzip")
+	}
+
+	var delay = 100 * time.Millisecond
+	for i := 0; ; i++ {
+		var retry, wait, err = w.post(client, header, body)
+		if !retry {
+			if err != nil {
+				atomic.AddUint64(&w.failed, uint64(b.count))
+				w.report("HTTPWriter", "send", w.url, err)
+			} else {
+				atomic.AddUint64(&w.sent, uint64(b.count))
+			}
+			return
+		}
+
+		if i >= maxRetries {
+			atomic.AddUint64(&w.failed, uint64(b.count))
+			w.report("HTTPWriter", "send", w.url, err)
+			return
+		}
+
+		//服务端给出了Retry-After时以其为准
+		if wait <= 0 {
+			wait = delay
+			delay *= 2
+		}
+		if wait > maxRetryWait {
+			wait = maxRetryWait
+		}
+
+		select {
+		case <-time.After(wait):
+		case <-w.stop:
+			atomic.AddUint64(&w.failed, uint64(b.count))
+			w.report("HTTPWriter", "send", w.url, err)
+			return
+		}
+	}
+}
+
+//post 进行一次请求。返回是否需要重试，服务端要求的等待时间，以及失败的原因
+func (w *HTTPWriter) post(client *http.Client, header http.Header, body []byte) (retry bool, wait time.Duration, err error) {
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return false, 0, err
+	}
+	req.Header = header
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return true, 0, err
+	}
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	_ = resp.Body.Close()
+
+	if resp.StatusCode < 300 {
+		return false, 0, nil
+	}
+
+	err = errors.New(resp.Status)
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		if s, e := strconv.Atoi(resp.Header.Get("Retry-After")); e == nil && s > 0 {
+			return true, time.Duration(s) * time.Second, err
+		}
+		return true, 0, err
+	}
+
+	return false, 0, err
+}
+
+//Flush 立即发送当前未满的一批，并等待发送完成
+func (w *HTTPWriter) Flush() {
+	w.mutex.Lock()
+	var closed = w.closed
+	w.mutex.Unlock()
+	if closed {
+		return
+	}
+
+	var done = make(chan struct{})
+	select {
+	case w.flushes <- done:
+		<-done
+	case <-w.done:
+	}
+}
+
+//flushSoon 通知后台协程立即发送当前未满的一批，不等待发送完成
+func (w *HTTPWriter) flushSoon() {
+	select {
+	case w.soon <- struct{}{}:
+	default:
+	}
+}
+
+//Close 发送所有未发送的记录后停止，需要重试的批次不再等待而计为失败
+func (w *HTTPWriter) Close() {
+	w.mutex.Lock()
+	if w.closed {
+		w.mutex.Unlock()
+		return
+	}
+	w.closed = true
+	w.mutex.Unlock()
+
+	close(w.stop)
+	<-w.done
+}
+
+//SetConfig 设置相关参数，已初始化的HTTPWriter返回Initialized
+func (w *HTTPWriter) SetConfig(config interface{}) error {
+	if config == nil {
+		return NotUnderstand("HTTPWriter:WriterPara")
+	}
+
+	w.mutex.Lock()
+	var running = w.done != nil
+	w.mutex.Unlock()
+	if running {
+		return Initialized("HTTPWriter")
+	}
+
+	var conf map[string]interface{}
+	switch config.(type) {
+	case map[string]interface{}:
+		conf = config.(map[string]interface{})
+	default:
+		return &MistakeType{"map[string]interface {} type", ""}
+	}
+
+	var url string
+	if val, ok := conf["URL"]; ok {
+		switch val.(type) {
+		case string:
+			url = val.(string)
+		default:
+			return &MistakeType{"string type", "URL"}
+		}
+	}
+	if url == "" {
+		return NotNil("URL")
+	}
+
+	var nums = map[string]int{
+		"BatchCount":   500,
+		"BatchSize":    1024,
+		"BatchLatency": 1000,
+		"MaxRetries":   3,
+		"Timeout":      10000,
+	}
+	for key := range nums {
+		if val, ok := conf[key]; ok {
+			switch val.(type) {
+			case float64:
+				v := int(val.(float64))
+				if v < 0 || (v == 0 && key != "MaxRetries") {
+					return &MistakeType{"大于0", strconv.Itoa(v)}
+				}
+				nums[key] = v
+			default:
+				return &MistakeType{"number type", key}
+			}
+		}
+	}
+
+	var useGzip = false
+	if val, ok := conf["Gzip"]; ok {
+		switch val.(type) {
+		case bool:
+			useGzip = val.(bool)
+		default:
+			return &MistakeType{"bool type", "Gzip"}
+		}
+	}
+
+	var headers = make(map[string]string)
+	if val, ok := conf["Headers"]; ok {
+		switch val.(type) {
+		case map[string]interface{}:
+			for k, v := range val.(map[string]interface{}) {
+				switch v.(type) {
+				case string:
+					headers[k] = v.(string)
+				default:
+					return &MistakeType{"string type", "Headers:" + k}
+				}
+			}
+		default:
+			return &MistakeType{"json type", "Headers"}
+		}
+	}
+
+	var username, password string
+	if val, ok := conf["Username"]; ok {
+		username, _ = val.(string)
+	}
+	if val, ok := conf["Password"]; ok {
+		password, _ = val.(string)
+	}
+
+	w.init(url)
+	//BatchSize以K为单位，BatchLatency与Timeout以毫秒为单位
+	w.SetBatch(nums["BatchCount"], nums["BatchSize"]*1024, time.Duration(nums["BatchLatency"])*time.Millisecond)
+	w.SetMaxRetries(nums["MaxRetries"])
+	w.SetClient(&http.Client{Timeout: time.Duration(nums["Timeout"]) * time.Millisecond})
+	w.SetGzip(useGzip)
+	for k, v := range headers {
+		w.SetHeader(k, v)
+	}
+	if username != "" {
+		w.SetBasicAuth(username, password)
+	}
+
+	return nil
+}
@@ -0,0 +1,207 @@
+package onelog
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHTTPWriterBatch(t *testing.T) {
+	var mutex sync.Mutex
+	var lines, requests int
+	var failOnce = true
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		//第一次请求返回503，检查是否会重试
+		if failOnce {
+			failOnce = false
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		if r.Header.Get("X-Token") != "abc" || r.Header.Get("Content-Type") != "application/x-ndjson" {
+			rw.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var body io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				rw.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			body = gr
+		}
+
+		requests++
+		s := bufio.NewScanner(body)
+		for s.Scan() {
+			lines++
+		}
+	}))
+	defer server.Close()
+
+	var hw = &HTTPWriter{}
+	err := hw.SetConfig(map[string]interface{}{
+		"URL":          server.URL,
+		"BatchCount":   float64(10),
+		"BatchLatency": float64(50),
+		"Gzip":         true,
+		"Headers":      map[string]interface{}{"X-Token": "abc"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var log = New(hw, InfoLevel, &JsonPattern{})
+	for i := 0; i < 25; i++ {
+		log.Info().Int("i", i).Msg("http")
+	}
+
+	//剩余的5条在最长等待时间后发送，第一批需要重试
+	for i := 0; i < 200 && hw.Sent() < 25; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if hw.Sent() != 25 {
+		t.Errorf("预期发送25条，实际%d条", hw.Sent())
+	}
+
+	log.Close()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if lines != 25 || requests != 3 {
+		t.Errorf("收到%d次请求，%d条记录", requests, lines)
+	}
+}
+
+func TestHTTPWriterFailed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	var hw = NewHTTPWriter(server.URL)
+	_, _ = hw.Write([]byte(`{"msg":"a"}` + "\n"))
+	hw.Flush()
+	hw.Close()
+
+	if hw.Failed() != 1 || hw.Sent() != 0 {
+		t.Errorf("4xx不应重试，发送%d条，失败%d条", hw.Sent(), hw.Failed())
+	}
+}
+
+func TestHTTPWriterRetryAfterClose(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Retry-After", "3600")
+		rw.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	var hw = NewHTTPWriter(server.URL).SetBatch(2, 0, time.Millisecond)
+	var written uint64
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				if _, err := hw.Write([]byte(`{"msg":"a"}` + "\n")); err == nil {
+					atomic.AddUint64(&written, 1)
+				}
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	//等待重试时关闭不应等待Retry-After的时间
+	var start = time.Now()
+	hw.Close()
+	wg.Wait()
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("关闭用时%v", d)
+	}
+
+	//写入成功的记录都应计入发送或失败，不能遗留在队列中
+	if total := hw.Sent() + hw.Failed(); total != atomic.LoadUint64(&written) {
+		t.Errorf("写入%d条，发送与失败共%d条", written, total)
+	}
+}
+
+func TestHTTPWriterErrorDoesNotWait(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	var hw = NewHTTPWriter(server.URL).SetBatch(0, 0, time.Hour)
+	var log = New(hw, InfoLevel, &JsonPattern{})
+	defer log.Close()
+
+	//ERROR记录只通知后台协程立即发送，不等待请求完成
+	var start = time.Now()
+	log.Error().Msg("failed")
+	if d := time.Since(start); d > 100*time.Millisecond {
+		t.Errorf("ERROR记录等待了%v", d)
+	}
+
+	for i := 0; i < 100 && hw.Sent() == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if hw.Sent() != 1 {
+		t.Errorf("未等待最长时间即应发送，实际发送%d条", hw.Sent())
+	}
+}
+
+func TestHTTPWriterBackedUp(t *testing.T) {
+	var release = make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+
+	var hw = NewHTTPWriter(server.URL).SetBatch(1, 0, time.Hour)
+
+	//服务端阻塞时写入不应等待，等待发送的批次已满后丢弃
+	var start = time.Now()
+	for i := 0; i < 20; i++ {
+		if _, err := hw.Write([]byte(`{"msg":"a"}` + "\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("写入等待了%v", d)
+	}
+	if hw.Failed() == 0 {
+		t.Error("发送跟不上时应丢弃并计入失败")
+	}
+
+	close(release)
+	hw.Close()
+	if total := hw.Sent() + hw.Failed(); total != 20 {
+		t.Errorf("写入20条，发送与失败共%d条", total)
+	}
+}
+
+func TestHTTPWriterConfigTwice(t *testing.T) {
+	var hw = &HTTPWriter{}
+	var conf = map[string]interface{}{"URL": "http://127.0.0.1:1/logs"}
+	if err := hw.SetConfig(conf); err != nil {
+		t.Fatal(err)
+	}
+	defer hw.Close()
+
+	if _, ok := hw.SetConfig(conf).(Initialized); !ok {
+		t.Error("再次设置应返回Initialized")
+	}
+}
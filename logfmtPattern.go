@@ -0,0 +1,112 @@
+package onelog
+
+import (
+	"math"
+	"strconv"
+	"unicode/utf8"
+)
+
+//LogfmtPattern logfmt的记录格式，每一项为key=value并以空格分隔。
+//值为空或包含空格、=、引号以及控制字符时使用双引号，并按JSON的规则转义
+type LogfmtPattern struct {
+}
+
+func (lf *LogfmtPattern) init(buffer []byte) []byte {
+	return buffer
+}
+
+//AppendKey 增加一个key的方法，key中的空格、=、引号以及控制字符将替换为_
+func (lf *LogfmtPattern) AppendKey(buffer []byte, key string) []byte {
+	if len(buffer) > 0 {
+		buffer = append(buffer, ' ')
+	}
+	if key == "" {
+		buffer = append(buffer, '_')
+	}
+
+	for i := 0; i < len(key); i++ {
+		if b := key[i]; b <= ' ' || b == '=' || b == '"' || b == 0x7f {
+			buffer = append(buffer, '_')
+		} else {
+			buffer = append(buffer, b)
+		}
+	}
+
+	return append(buffer, '=')
+}
+
+//AppendValue 增加一个[]byte数组值的方法
+func (lf *LogfmtPattern) AppendValue(buffer []byte, value []byte) []byte {
+	return appendLogfmtValue(buffer, value)
+}
+
+//AppendUint64 将一个uint64值记录缓存中
+func (lf *LogfmtPattern) AppendUint64(buffer []byte, value uint64, base int) []byte {
+	return strconv.AppendUint(buffer, value, base)
+}
+
+//AppendUint32 将一个uint32值记录缓存中
+func (lf *LogfmtPattern) AppendUint32(buffer []byte, value uint32, base int) []byte {
+	return strconv.AppendUint(buffer, uint64(value), base)
+}
+
+//AppendFloat64 将一个float64值记录缓存中
+func (lf *LogfmtPattern) AppendFloat64(buffer []byte, val float64) []byte {
+	switch {
+	case math.IsNaN(val):
+		return append(buffer, "NaN"...)
+	case math.IsInf(val, 1):
+		return append(buffer, "+Inf"...)
+	case math.IsInf(val, -1):
+		return append(buffer, "-Inf"...)
+	}
+
+	return strconv.AppendFloat(buffer, val, 'f', -1, 64)
+}
+
+//AppendInt64 将一个int64的值插入至数据内
+func (lf *LogfmtPattern) AppendInt64(buffer []byte, value int64, base int) []byte {
+	return strconv.AppendInt(buffer, value, base)
+}
+
+//AppendString 将一个string的值插入至数据内
+func (lf *LogfmtPattern) AppendString(buffer []byte, value string) []byte {
+	return appendLogfmtValue(buffer, []byte(value))
+}
+
+func (lf *LogfmtPattern) Complete(buffer []byte) []byte {
+	return append(buffer, '\n')
+}
+
+func (lf *LogfmtPattern) addRuntimeValues(buffer []byte, r RunTimeCompute) []byte {
+	buffer = lf.AppendKey(buffer, r.GetName())
+	buffer = lf.AppendValue(buffer, r.Values())
+
+	return buffer
+}
+
+//appendLogfmtValue 需要时使用双引号写入值
+func appendLogfmtValue(buffer []byte, value []byte) []byte {
+	if !needsQuote(value) {
+		return append(buffer, value...)
+	}
+
+	buffer = append(buffer, '"')
+	buffer = appendStringComplex(buffer, value, 0)
+	return append(buffer, '"')
+}
+
+//needsQuote 值为空或包含空格、=、引号、反斜杠、控制字符以及无效的UTF-8时需要双引号
+func needsQuote(value []byte) bool {
+	if len(value) == 0 {
+		return true
+	}
+
+	for _, b := range value {
+		if b <= ' ' || b == '=' || b == '"' || b == '\\' || b == 0x7f {
+			return true
+		}
+	}
+
+	return !utf8.Valid(value)
+}
@@ -0,0 +1,49 @@
+package onelog
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLogfmtPattern(t *testing.T) {
+	var buf bytes.Buffer
+	var log = New(&Stdout{&buf}, DebugLevel, &LogfmtPattern{})
+	log.AddStatic("app", "demo")
+
+	log.Info().
+		String("user", "a b").
+		String("query", "x=1").
+		String("quote", `say "hi"`).
+		String("empty", "").
+		String("bad key", "中文").
+		Int("n", -3).
+		Bool("ok", true).
+		Float64("f", 1.5).
+		Error(errors.New("no such file")).
+		Msg("line\nbreak")
+
+	var line = buf.String()
+	if !strings.HasSuffix(line, "\n") || strings.Count(line, "\n") != 1 {
+		t.Fatalf("应为一行:%q", line)
+	}
+	for _, want := range []string{`app=demo`, `user="a b"`, `query="x=1"`, `quote="say \"hi\""`, `empty=""`, `bad_key=中文`,
+		`n=-3`, `ok=true`, `f=1.5`, `err="no such file"`, `msg="line\nbreak"`} {
+		if !strings.Contains(line, " "+want) {
+			t.Errorf("缺少%s:%s", want, line)
+		}
+	}
+	if !strings.HasPrefix(line, LevelName+"=INFO ") {
+		t.Errorf("第一项前不应有空格:%s", line)
+	}
+}
+
+func TestLogfmtConfig(t *testing.T) {
+	if _, ok := refPattern["logfmt"]; !ok {
+		t.Fatal("未注册logfmt")
+	}
+	if err := checkCorrect("test", "info", "logfmt", "console"); err != nil {
+		t.Error(err)
+	}
+}
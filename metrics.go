@@ -0,0 +1,239 @@
+package onelog
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+//Stats Logger的统计，Records与Dropped以等级为下标
+type Stats struct {
+	//Records 已写入的记录条数
+	Records [Disable]uint64
+	//Dropped 被采样、限流或去重丢弃的记录条数
+	Dropped [Disable]uint64
+	//Errors 写入记录时出现的错误数
+	Errors  uint64
+	Writers []WriterStats
+}
+
+//WriterStats 一个Writer的统计，Path为文件名或地址
+type WriterStats struct {
+	Writer    string
+	Path      string
+	Written   uint64
+	Errors    uint64
+	Dropped   uint64
+	Rotations uint64
+}
+
+//statsWriter 可提供统计的Writer
+type statsWriter interface {
+	stats() WriterStats
+}
+
+//loggerStats Logger中按等级的计数，由其所有的LevelWriter共用
+type loggerStats struct {
+	records [Disable]uint64
+	dropped [Disable]uint64
+}
+
+func (s *loggerStats) addRecord(level Level) {
+	if s != nil && level < Disable {
+		atomic.AddUint64(&s.records[level], 1)
+	}
+}
+
+func (s *loggerStats) addDropped(level Level) {
+	if s != nil && level < Disable {
+		atomic.AddUint64(&s.dropped[level], 1)
+	}
+}
+
+//writerCounters 嵌入至Writer中的计数
+type writerCounters struct {
+	written   uint64
+	rotations uint64
+}
+
+func (c *writerCounters) addWritten(n int) {
+	atomic.AddUint64(&c.written, uint64(n))
+}
+
+func (c *writerCounters) counters(writer, path string, errors, dropped uint64) WriterStats {
+	return WriterStats{
+		Writer:    writer,
+		Path:      path,
+		Written:   atomic.LoadUint64(&c.written),
+		Errors:    errors,
+		Dropped:   dropped,
+		Rotations: atomic.LoadUint64(&c.rotations),
+	}
+}
+
+//Stats 返回此Logger自创建以来的统计，包含其中所有可提供统计的Writer
+func (l *Logger) Stats() Stats {
+	if l.base != nil {
+		return l.base.Stats()
+	}
+
+	var s = Stats{Errors: l.errors.failures()}
+	for i := TraceLevel; i < Disable; i++ {
+		s.Records[i] = atomic.LoadUint64(&l.stats.records[i])
+		s.Dropped[i] = atomic.LoadUint64(&l.stats.dropped[i])
+	}
+
+	eachWriter(l.writer, func(w Writer) {
+		if sw, ok := w.(statsWriter); ok {
+			s.Writers = append(s.Writers, sw.stats())
+		}
+	})
+
+	return s
+}
+
+func (w *FileWriter) stats() WriterStats {
+	return w.counters("FileWriter", w.fileName, w.failures(), 0)
+}
+
+func (w *NetWriter) stats() WriterStats {
+	return w.counters("NetWriter", w.address, w.failures(), w.Dropped())
+}
+
+func (w *HTTPWriter) stats() WriterStats {
+	return w.counters("HTTPWriter", w.url, w.failures(), w.Failed())
+}
+
+func (s *SyslogWriter) stats() WriterStats {
+	return s.counters("SyslogWriter", s.address, s.Failed(), 0)
+}
+
+//stdoutCounters 每个Stdout的计数。Stdout只能有Writer一项，以兼容&Stdout{os.Stdout}这样不带名称的写法，计数保存在此处
+var stdoutCounters sync.Map
+
+type stdoutCount struct {
+	writerCounters
+	errors uint64
+}
+
+func (s *Stdout) counter() *stdoutCount {
+	if c, ok := stdoutCounters.Load(s); ok {
+		return c.(*stdoutCount)
+	}
+
+	c, _ := stdoutCounters.LoadOrStore(s, &stdoutCount{})
+	return c.(*stdoutCount)
+}
+
+//count 记录一次写入的字节数与是否出错
+func (s *Stdout) count(n int, err error) {
+	var c = s.counter()
+	c.addWritten(n)
+	if err != nil {
+		atomic.AddUint64(&c.errors, 1)
+	}
+}
+
+func (s *Stdout) stats() WriterStats {
+	var path string
+	if f, ok := s.Writer.(*os.File); ok {
+		path = f.Name()
+	}
+
+	var c = s.counter()
+	return c.counters("Stdout", path, atomic.LoadUint64(&c.errors), 0)
+}
+
+func (a *AsyncWriter) stats() WriterStats {
+	return a.counters("AsyncWriter", "", a.failures(), a.Dropped())
+}
+
+//MetricsHandler 返回以Prometheus文本格式输出所有SaveLogList保存的Logger统计的http.Handler，
+//logger标签为保存时的名称
+func MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = WriteMetrics(w)
+	})
+}
+
+type metric struct {
+	name, help string
+	lines      []string
+}
+
+//WriteMetrics 以Prometheus文本格式写出所有SaveLogList保存的Logger统计。
+//Writer的index标签为其在此Logger中的序号，类型与路径都相同的多个Writer以此区分
+func WriteMetrics(writer io.Writer) error {
+	logsMutex.RLock()
+	var names = make([]string, 0, len(logs))
+	var saved = make(map[string]*Logger, len(logs))
+	for name, log := range logs {
+		names = append(names, name)
+		saved[name] = log
+	}
+	logsMutex.RUnlock()
+	sort.Strings(names)
+
+	var metrics = []*metric{
+		{name: "onelog_records_total", help: "已写入的记录条数"},
+		{name: "onelog_dropped_records_total", help: "被采样、限流或去重丢弃的记录条数"},
+		{name: "onelog_errors_total", help: "写入记录时出现的错误数"},
+		{name: "onelog_writer_bytes_total", help: "Writer写入的字节数"},
+		{name: "onelog_writer_errors_total", help: "Writer出现的错误数"},
+		{name: "onelog_writer_dropped_records_total", help: "Writer丢弃的记录条数"},
+		{name: "onelog_writer_rotations_total", help: "FileWriter切分文件的次数"},
+	}
+	var add = func(i int, labels string, value uint64) {
+		metrics[i].lines = append(metrics[i].lines, metrics[i].name+"{"+labels+"} "+strconv.FormatUint(value, 10))
+	}
+
+	for _, name := range names {
+		var s = saved[name].Stats()
+		var logger = label("logger", name)
+
+		for i := TraceLevel; i < Disable; i++ {
+			var labels = logger + "," + label("level", i.String())
+			add(0, labels, s.Records[i])
+			add(1, labels, s.Dropped[i])
+		}
+		add(2, logger, s.Errors)
+
+		for i, ws := range s.Writers {
+			var labels = logger + "," + label("writer", ws.Writer) + "," + label("path", ws.Path) + "," + label("index", strconv.Itoa(i))
+			add(3, labels, ws.Written)
+			add(4, labels, ws.Errors)
+			add(5, labels, ws.Dropped)
+			if ws.Writer == "FileWriter" {
+				add(6, labels, ws.Rotations)
+			}
+		}
+	}
+
+	var buf = bufio.NewWriter(writer)
+	for _, m := range metrics {
+		if len(m.lines) == 0 {
+			continue
+		}
+		buf.WriteString("# HELP " + m.name + " " + m.help + "\n")
+		buf.WriteString("# TYPE " + m.name + " counter\n")
+		for _, line := range m.lines {
+			buf.WriteString(line + "\n")
+		}
+	}
+
+	return buf.Flush()
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+//label 生成一个Prometheus标签
+func label(name, value string) string {
+	return name + `="` + labelEscaper.Replace(value) + `"`
+}
@@ -0,0 +1,146 @@
+package onelog
+
+import (
+	"bytes"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestLoggerStats(t *testing.T) {
+	var dir = t.TempDir()
+	fw, err := NewFileWriter(dir+"/stats.log", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var log = New(&Stdout{&bytes.Buffer{}}, DebugLevel, &JsonPattern{})
+	log.AddWriter(fw)
+	log.SetSampler(&EverySampler{N: 2}, DebugLevel)
+
+	for i := 0; i < 10; i++ {
+		log.Debug().Msg("debug")
+		log.Info().Msg("info")
+	}
+	log.Error().Msg("error")
+	fw.Close()
+	log.Warn().Msg("closed")
+
+	var s = log.Stats()
+	if s.Records[DebugLevel] != 5 || s.Dropped[DebugLevel] != 5 || s.Records[InfoLevel] != 10 || s.Records[ErrorLevel] != 1 {
+		t.Errorf("等级计数不正确:%v %v", s.Records, s.Dropped)
+	}
+	if s.Errors != 1 {
+		t.Errorf("写入错误数不正确:%d", s.Errors)
+	}
+	var writers = make(map[string]WriterStats)
+	for _, w := range s.Writers {
+		writers[w.Writer] = w
+	}
+	if len(s.Writers) != 2 || writers["FileWriter"].Written == 0 {
+		t.Fatalf("%+v", s.Writers)
+	}
+	if writers["Stdout"].Written <= writers["FileWriter"].Written {
+		t.Errorf("关闭FileWriter后Stdout仍应写入:%+v", s.Writers)
+	}
+}
+
+func TestStdoutStats(t *testing.T) {
+	var log = New(&Stdout{&failWriter{}}, InfoLevel, &JsonPattern{})
+	SetErrorHandler(func(*WriterError) {})
+	defer SetErrorHandler(nil)
+
+	log.Info().Msg("a")
+	log.Info().Msg("b")
+
+	var s = log.Stats()
+	if len(s.Writers) != 1 || s.Writers[0].Writer != "Stdout" || s.Writers[0].Errors != 2 || s.Writers[0].Written != 0 {
+		t.Errorf("%+v", s.Writers)
+	}
+}
+
+func TestWriteMetrics(t *testing.T) {
+	var log = New(&Stdout{&bytes.Buffer{}}, InfoLevel, &JsonPattern{})
+	var fw, _ = NewFileWriter(t.TempDir()+"/metrics.log", 1)
+	fw.maxCapacity = 100
+	fw.SetCodec(NoCompression{})
+	log.AddWriter(fw)
+	SaveLogList(`metrics"test`, log)
+	defer func() {
+		logsMutex.Lock()
+		delete(logs, `metrics"test`)
+		logsMutex.Unlock()
+	}()
+
+	for i := 0; i < 20; i++ {
+		log.Info().Msg(strings.Repeat("x", 50))
+	}
+	log.Error().Error(errors.New("x")).Msg("failed")
+	log.Flush()
+
+	var rec = httptest.NewRecorder()
+	MetricsHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	var body = rec.Body.String()
+
+	for _, want := range []string{
+		"# TYPE onelog_records_total counter",
+		`onelog_records_total{logger="metrics\"test",level="INFO"} 20`,
+		`onelog_records_total{logger="metrics\"test",level="DEBUG"} 0`,
+		`onelog_errors_total{logger="metrics\"test"} 0`,
+		`onelog_writer_bytes_total{logger="metrics\"test",writer="FileWriter",path="` + fw.fileName + `",index="0"}`,
+		`onelog_writer_rotations_total{logger="metrics\"test",writer="FileWriter"`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("缺少%s:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, `onelog_writer_rotations_total{logger="metrics\"test",writer="FileWriter",path="`+fw.fileName+`",index="0"} 0`) {
+		t.Error("应有切分次数")
+	}
+	fw.Close()
+}
+
+func TestWriteMetricsUniqueSeries(t *testing.T) {
+	var log = New(NewAsyncWriter(&Stdout{os.Stdout}, 8, OverflowDropNewest), InfoLevel, &JsonPattern{})
+	log.AddWriter(NewAsyncWriter(&Stdout{os.Stdout}, 8, OverflowDropNewest))
+	defer log.Close()
+
+	//读取统计的同时保存Logger
+	var done = make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 50; i++ {
+			SaveLogList("unique"+strconv.Itoa(i%2), log)
+		}
+	}()
+
+	var buf bytes.Buffer
+	for i := 0; i < 20; i++ {
+		buf.Reset()
+		_ = WriteMetrics(&buf)
+	}
+	<-done
+	defer func() {
+		logsMutex.Lock()
+		delete(logs, "unique0")
+		delete(logs, "unique1")
+		logsMutex.Unlock()
+	}()
+
+	buf.Reset()
+	_ = WriteMetrics(&buf)
+	var series = make(map[string]bool)
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var key = line[:strings.LastIndexByte(line, ' ')]
+		if series[key] {
+			t.Errorf("重复的序列:%s", key)
+		}
+		series[key] = true
+	}
+}
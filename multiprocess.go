@@ -0,0 +1,115 @@
+package onelog
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//SetMultiProcess 设置多进程模式。多个进程使用同一个日志文件时需要开启，开启后写入与切分都在文件锁
+//<FileName>.lock 的保护下进行：切分只由一个进程完成，其他进程发现文件已被切分(inode变化)后重新打开新文件
+func (w *FileWriter) SetMultiProcess(enable bool) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if !enable {
+		w.closeLock()
+		return nil
+	}
+	if w.lock != nil {
+		return nil
+	}
+	//各进程按各自的时间生成文件名时无法保证写入同一个文件
+	if w.activeName != "" {
+		return NotUnderstand("MultiProcess:ActiveName")
+	}
+
+	f, err := os.OpenFile(w.fileName+".lock", os.O_RDWR|os.O_CREATE, 0666)
+	if err != nil {
+		return err
+	}
+	w.lock = f
+
+	return nil
+}
+
+func (w *FileWriter) closeLock() {
+	if w.lock != nil {
+		_ = w.lock.Close()
+		w.lock = nil
+	}
+}
+
+//lockedWriteToDisk 多进程模式下将缓存写入文件，整个缓存在持有文件锁时一次写入，不会与其他进程的内容交错
+func (w *FileWriter) lockedWriteToDisk(isClose bool) {
+	//加锁失败时仍然写入，只是不再与其他进程协调
+	if err := lockFile(w.lock); err == nil {
+		defer func() { _ = unlockFile(w.lock) }()
+	} else {
+		w.report("FileWriter", "lock", w.lock.Name(), err)
+	}
+
+	w.reopenIfRotated()
+
+	if w.schedule != nil && !time.Now().Before(w.nextRotate) {
+		//上一时间段的内容写入旧文件
+		w.writeBuffer()
+
+		var boundary = w.nextRotate
+		if w.rotatedUntil().Before(boundary) {
+			w.rotateByTime(isClose)
+			w.markRotated(boundary)
+		} else {
+			//其他进程已完成此次切分
+			var now = time.Now()
+			w.periodStart = lastBoundary(w.schedule, now)
+			w.nextRotate = w.schedule.Next(now)
+		}
+	}
+
+	if w.maxCapacity > 0 {
+		if this, e := w.file.Stat(); e == nil && this.Size() > w.maxCapacity {
+			w.rotate(isClose)
+		}
+	}
+
+	w.writeBuffer()
+}
+
+//reopenIfRotated 当前打开的文件已被其他进程改名时，重新打开日志文件
+func (w *FileWriter) reopenIfRotated() {
+	opened, err := w.file.Stat()
+	if err != nil {
+		return
+	}
+	if current, err := os.Stat(w.active); err == nil && os.SameFile(opened, current) {
+		return
+	}
+
+	if f, err := createLogWriteFile(w.active); err == nil {
+		_ = w.file.Close()
+		w.file = f
+	} else {
+		w.report("FileWriter", "open", w.active, err)
+	}
+}
+
+//rotatedUntil 读取锁文件中记录的最后一次按时间切分的时间点
+func (w *FileWriter) rotatedUntil() time.Time {
+	var buf = make([]byte, 32)
+	n, _ := w.lock.ReadAt(buf, 0)
+
+	sec, err := strconv.ParseInt(strings.TrimSpace(string(buf[:n])), 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return time.Unix(sec, 0)
+}
+
+//markRotated 在锁文件中记录已完成的按时间切分的时间点，避免其他进程重复切分
+func (w *FileWriter) markRotated(boundary time.Time) {
+	_ = w.lock.Truncate(0)
+	_, _ = w.lock.WriteAt([]byte(strconv.FormatInt(boundary.Unix(), 10)), 0)
+}
@@ -0,0 +1,134 @@
+package onelog
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFileWriterMultiProcess(t *testing.T) {
+	var dir = t.TempDir()
+	var name = filepath.Join(dir, "shared.log")
+
+	//同一进程内两次打开锁文件与两个进程的效果相同
+	var writers = make([]*FileWriter, 2)
+	for i := range writers {
+		fw, err := NewFileWriter(name, 4*1024)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = fw.SetMultiProcess(true); err != nil {
+			t.Fatal(err)
+		}
+		writers[i] = fw
+	}
+
+	var wg sync.WaitGroup
+	for i, fw := range writers {
+		wg.Add(1)
+		go func(id int, fw *FileWriter) {
+			defer wg.Done()
+			for n := 0; n < 500; n++ {
+				_, _ = fw.Write([]byte("writer" + strconv.Itoa(id) + " line " + strconv.Itoa(n) + " " + strings.Repeat("x", 40) + "\n"))
+				if n%50 == 0 {
+					fw.Flush()
+				}
+			}
+		}(i, fw)
+	}
+	wg.Wait()
+	for _, fw := range writers {
+		fw.Close()
+	}
+
+	var lines = readAllLogLines(t, dir, "shared.log")
+	if len(lines) != 1000 {
+		t.Fatalf("预期1000行，实际%d行", len(lines))
+	}
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "writer") || !strings.HasSuffix(line, strings.Repeat("x", 40)) {
+			t.Fatalf("内容交错:%q", line)
+		}
+	}
+}
+
+func TestFileWriterReopenAfterRotate(t *testing.T) {
+	var name = filepath.Join(t.TempDir(), "reopen.log")
+
+	a, _ := NewFileWriter(name, 1024*1024)
+	b, _ := NewFileWriter(name, 1024*1024)
+	_ = a.SetMultiProcess(true)
+	_ = b.SetMultiProcess(true)
+	defer a.Close()
+	defer b.Close()
+
+	_, _ = b.Write([]byte("before\n"))
+	b.Flush()
+
+	//模拟另一个进程完成了切分
+	a.mutex.Lock()
+	a.rotate(true)
+	a.mutex.Unlock()
+
+	_, _ = b.Write([]byte("after\n"))
+	b.Flush()
+
+	if data, _ := ioutil.ReadFile(name); string(data) != "after\n" {
+		t.Errorf("切分后应写入新文件:%q", data)
+	}
+	//按大小切分的归档默认使用包含年份的日期
+	if archives, _ := filepath.Glob(name + "." + time.Now().Format("20060102") + "_1*"); len(archives) != 1 {
+		t.Errorf("归档文件名不正确:%v", archives)
+	}
+}
+
+//readAllLogLines 读取当前日志文件与全部归档文件中的行，等待后台压缩完成
+func readAllLogLines(t *testing.T, dir, base string) []string {
+	var deadline = time.Now().Add(5 * time.Second)
+	for {
+		plain, _ := filepath.Glob(filepath.Join(dir, base+".*_*[0-9]"))
+		if len(plain) == 0 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	var lines []string
+	var read = func(r io.Reader) {
+		s := bufio.NewScanner(r)
+		for s.Scan() {
+			lines = append(lines, s.Text())
+		}
+	}
+
+	archives, _ := filepath.Glob(filepath.Join(dir, base+".*.gz"))
+	for _, path := range archives {
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gr, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		read(gr)
+		_ = f.Close()
+	}
+
+	f, err := os.Open(filepath.Join(dir, base))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	read(f)
+
+	return lines
+}
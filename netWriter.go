@@ -0,0 +1,490 @@
+package onelog
+
+import (
+	"encoding/binary"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//Framing 网络写入时每条记录的分帧方式
+type Framing uint8
+
+const (
+	//FramingNewline 每条记录以换行结束
+	FramingNewline Framing = iota
+	//FramingLength 每条记录前增加4字节大端序的长度
+	FramingLength
+)
+
+//netWriteTimeout 每次发送的超时时间，超时后断开连接并存入磁盘队列
+const netWriteTimeout = 5 * time.Second
+
+//NetWriter 通过TCP或unix socket发送日志的Writer。连接断开时将以指数退避的方式重连，
+//断开期间的记录存入磁盘上的有界队列，重连后按顺序重新发送。同一时间只有一个协程发送，并设置超时，
+//发送失败的记录在其他协程发送之前存入队列，保证记录的顺序
+type NetWriter struct {
+	network      string
+	address      string
+	framing      Framing
+	conn         net.Conn
+	spool        *diskSpool
+	minBackoff   time.Duration
+	maxBackoff   time.Duration
+	reconnecting bool
+	closed       bool
+	dropped      uint64
+	stop         chan struct{}
+	mutex        sync.Mutex
+	//send 使发送串行进行，发送与失败后存入队列期间一直持有
+	send sync.Mutex
+	errorReporter
+	writerCounters
+}
+
+//NewNetWriter 返回一个新的NetWriter，network可为tcp或unix。spoolFile为空时断开期间的记录将被丢弃，
+//spoolSize为磁盘队列最大的字节数。连接失败时不返回错误，将在后台重连
+func NewNetWriter(network, address string, framing Framing, spoolFile string, spoolSize int64) (*NetWriter, error) {
+	var w = &NetWriter{}
+	if err := w.init(network, address, framing, spoolFile, spoolSize); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+//init 初始化参数并进行第一次连接
+func (w *NetWriter) init(network, address string, framing Framing, spoolFile string, spoolSize int64) error {
+	switch network {
+	case "tcp", "unix":
+	default:
+		return NotUnderstand("Network:" + network)
+	}
+
+	var spool *diskSpool
+	if spoolFile != "" {
+		var err error
+		if spool, err = openSpool(spoolFile, spoolSize); err != nil {
+			return err
+		}
+	}
+
+	w.mutex.Lock()
+	w.network = network
+	w.address = address
+	w.framing = framing
+	w.spool = spool
+	w.minBackoff = 100 * time.Millisecond
+	w.maxBackoff = 30 * time.Second
+	w.stop = make(chan struct{})
+	w.reconnecting = true
+	w.mutex.Unlock()
+
+	//上次未发送完的记录需要先发送
+	if err := w.connect(); err != nil {
+		go w.reconnect()
+	}
+
+	return nil
+}
+
+//SetMaxBackoff 设置重连的最长间隔
+func (w *NetWriter) SetMaxBackoff(max time.Duration) *NetWriter {
+	w.mutex.Lock()
+	w.maxBackoff = max
+	w.mutex.Unlock()
+
+	return w
+}
+
+//Dropped 返回断开期间因没有磁盘队列或队列已满被丢弃的记录条数
+func (w *NetWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&w.dropped)
+}
+
+func (w *NetWriter) Write(p []byte) (n int, err error) {
+	w.send.Lock()
+	defer w.send.Unlock()
+
+	w.mutex.Lock()
+	if w.closed {
+		w.mutex.Unlock()
+		return 0, Closed("NetWriter")
+	}
+
+	//没有连接或队列中还有记录时需要排在其后，保证顺序
+	var conn = w.conn
+	if conn == nil {
+		w.spoolRecord(p)
+		w.mutex.Unlock()
+		return len(p), nil
+	}
+	var frame = w.appendFrame(nil, p)
+	w.mutex.Unlock()
+
+	_ = conn.SetWriteDeadline(time.Now().Add(netWriteTimeout))
+	if _, err = conn.Write(frame); err == nil {
+		w.addWritten(len(p))
+		return len(p), nil
+	}
+
+	w.mutex.Lock()
+	w.report("NetWriter", "write", w.address, err)
+	if w.conn == conn {
+		w.disconnect()
+	}
+	if !w.closed {
+		w.spoolRecord(p)
+	}
+	w.mutex.Unlock()
+
+	return len(p), nil
+}
+
+//spoolRecord 将记录存入磁盘队列，没有队列或队列已满时丢弃，调用方需要持有锁
+func (w *NetWriter) spoolRecord(p []byte) {
+	if w.spool == nil || !w.spool.push(p) {
+		atomic.AddUint64(&w.dropped, 1)
+		w.report("NetWriter", "drop", w.address, NotNil("可用的连接或磁盘队列"))
+	}
+}
+
+//appendFrame 按分帧方式生成要发送的数据
+func (w *NetWriter) appendFrame(buf []byte, p []byte) []byte {
+	switch w.framing {
+	case FramingLength:
+		buf = append(buf, 0, 0, 0, 0)
+		binary.BigEndian.PutUint32(buf[len(buf)-4:], uint32(len(p)))
+		buf = append(buf, p...)
+	default:
+		buf = append(buf, p...)
+		if len(p) == 0 || p[len(p)-1] != '\n' {
+			buf = append(buf, '\n')
+		}
+	}
+
+	return buf
+}
+
+//connect 建立连接，并在发送完磁盘队列中的记录后才开始直接发送
+func (w *NetWriter) connect() error {
+	conn, err := net.DialTimeout(w.network, w.address, 5*time.Second)
+	if err != nil {
+		return err
+	}
+
+	if err = w.replay(conn); err != nil {
+		_ = conn.Close()
+		return err
+	}
+
+	return nil
+}
+
+//replay 不持有锁按顺序发送磁盘队列中的记录，此期间的新记录继续存入队列。
+//队列为空时在同一次加锁中开始使用此连接，保证记录的顺序
+func (w *NetWriter) replay(conn net.Conn) error {
+	for {
+		w.mutex.Lock()
+		if w.closed {
+			w.mutex.Unlock()
+			return Closed("NetWriter")
+		}
+		if w.spool == nil || w.spool.empty() {
+			w.conn = conn
+			w.reconnecting = false
+			w.mutex.Unlock()
+			return nil
+		}
+
+		p, err := w.spool.peek()
+		if err != nil {
+			//无法读取的队列不再重试
+			w.report("NetWriter", "replay", w.address, err)
+			w.spool.reset()
+			w.mutex.Unlock()
+			continue
+		}
+		var frame = w.appendFrame(nil, p)
+		w.mutex.Unlock()
+
+		_ = conn.SetWriteDeadline(time.Now().Add(netWriteTimeout))
+		if _, err = conn.Write(frame); err != nil {
+			return err
+		}
+
+		w.mutex.Lock()
+		w.spool.pop(len(p))
+		w.mutex.Unlock()
+	}
+}
+
+//disconnect 关闭当前连接并启动后台重连，调用方需要持有锁
+func (w *NetWriter) disconnect() {
+	if w.conn != nil {
+		_ = w.conn.Close()
+		w.conn = nil
+	}
+
+	if !w.reconnecting && !w.closed {
+		w.reconnecting = true
+		go w.reconnect()
+	}
+}
+
+//reconnect 以指数退避的方式重连，连接成功后先发送磁盘队列中的记录
+func (w *NetWriter) reconnect() {
+	w.mutex.Lock()
+	var delay = w.minBackoff
+	w.mutex.Unlock()
+
+	for {
+		select {
+		case <-time.After(delay):
+		case <-w.stop:
+			return
+		}
+
+		w.mutex.Lock()
+		if delay *= 2; delay > w.maxBackoff {
+			delay = w.maxBackoff
+		}
+		w.mutex.Unlock()
+
+		if err := w.connect(); err == nil {
+			return
+		} else if _, ok := err.(Closed); ok {
+			return
+		}
+	}
+}
+
+func (*NetWriter) Flush() {
+}
+
+//Close 关闭连接，未发送的记录保留在磁盘队列中，下次启动时发送
+func (w *NetWriter) Close() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.closed {
+		return
+	}
+	w.closed = true
+	close(w.stop)
+
+	if w.conn != nil {
+		_ = w.conn.Close()
+		w.conn = nil
+	}
+	if w.spool != nil {
+		w.spool.close()
+	}
+}
+
+//SetConfig 设置相关参数，已初始化的NetWriter返回Initialized
+func (w *NetWriter) SetConfig(config interface{}) error {
+	if config == nil {
+		return NotUnderstand("NetWriter:WriterPara")
+	}
+
+	w.mutex.Lock()
+	var running = w.stop != nil
+	w.mutex.Unlock()
+	if running {
+		return Initialized("NetWriter")
+	}
+
+	var conf map[string]interface{}
+	switch config.(type) {
+	case map[string]interface{}:
+		conf = config.(map[string]interface{})
+	default:
+		return &MistakeType{"map[string]interface {} type", ""}
+	}
+
+	var strs = map[string]string{
+		"Network":   "tcp",
+		"Address":   "",
+		"Framing":   "newline",
+		"SpoolFile": "",
+	}
+	for key := range strs {
+		if val, ok := conf[key]; ok {
+			switch val.(type) {
+			case string:
+				strs[key] = val.(string)
+			default:
+				return &MistakeType{"string type", key}
+			}
+		}
+	}
+
+	if strs["Address"] == "" {
+		return NotNil("Address")
+	}
+
+	var framing Framing
+	switch strings.ToLower(strs["Framing"]) {
+	case "newline":
+		framing = FramingNewline
+	case "length":
+		framing = FramingLength
+	default:
+		return NotUnderstand("Framing:" + strs["Framing"])
+	}
+
+	//以M为单位
+	var spoolSize = 64
+	var maxBackoff = 30 * time.Second
+	for _, key := range []string{"SpoolSize", "MaxBackoff"} {
+		if val, ok := conf[key]; ok {
+			switch val.(type) {
+			case float64:
+				v := int(val.(float64))
+				if v <= 0 {
+					return &MistakeType{"大于0", strconv.Itoa(v)}
+				}
+				if key == "SpoolSize" {
+					spoolSize = v
+				} else {
+					//以毫秒为单位
+					maxBackoff = time.Duration(v) * time.Millisecond
+				}
+			default:
+				return &MistakeType{"number type", key}
+			}
+		}
+	}
+
+	if err := w.init(strings.ToLower(strs["Network"]), strs["Address"], framing, strs["SpoolFile"], int64(spoolSize)*1024*1024); err != nil {
+		return err
+	}
+	w.SetMaxBackoff(maxBackoff)
+
+	return nil
+}
+
+//diskSpool 磁盘上的有界队列，每条记录以4字节长度开头
+type diskSpool struct {
+	file    *os.File
+	maxSize int64
+	size    int64
+	offset  int64
+}
+
+func openSpool(path string, maxSize int64) (*diskSpool, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0666)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	var s = &diskSpool{file: f, maxSize: maxSize, size: info.Size()}
+	if err = s.truncatePartial(); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+//truncatePartial 检查每一条记录，去掉异常退出时末尾写入不完整的记录
+func (s *diskSpool) truncatePartial() error {
+	var head = make([]byte, 4)
+	var offset int64
+
+	for offset+4 <= s.size {
+		if _, err := s.file.ReadAt(head, offset); err != nil {
+			return err
+		}
+
+		var next = offset + 4 + int64(binary.BigEndian.Uint32(head))
+		if next > s.size {
+			break
+		}
+		offset = next
+	}
+
+	if offset < s.size {
+		if err := s.file.Truncate(offset); err != nil {
+			return err
+		}
+		s.size = offset
+	}
+
+	return nil
+}
+
+func (s *diskSpool) empty() bool {
+	return s.offset >= s.size
+}
+
+//push 在队列末尾增加一条记录，超过最大容量时返回false
+func (s *diskSpool) push(p []byte) bool {
+	if s.maxSize > 0 && s.size+int64(len(p))+4 > s.maxSize {
+		return false
+	}
+
+	var buf = make([]byte, 4, len(p)+4)
+	binary.BigEndian.PutUint32(buf, uint32(len(p)))
+	buf = append(buf, p...)
+
+	if _, err := s.file.WriteAt(buf, s.size); err != nil {
+		return false
+	}
+	s.size += int64(len(buf))
+
+	return true
+}
+
+//peek 读取队列中未发送的第一条记录
+func (s *diskSpool) peek() ([]byte, error) {
+	var head = make([]byte, 4)
+	if _, err := s.file.ReadAt(head, s.offset); err != nil {
+		return nil, err
+	}
+
+	var buf = make([]byte, binary.BigEndian.Uint32(head))
+	if _, err := s.file.ReadAt(buf, s.offset+4); err != nil {
+		return nil, err
+	}
+
+	return buf, nil
+}
+
+//pop 去掉已发送的第一条记录，全部发送后清空文件
+func (s *diskSpool) pop(length int) {
+	s.offset += int64(length) + 4
+	if s.offset >= s.size {
+		s.reset()
+	}
+}
+
+//reset 清空队列
+func (s *diskSpool) reset() {
+	_ = s.file.Truncate(0)
+	s.size, s.offset = 0, 0
+}
+
+func (s *diskSpool) close() {
+	//已发送的部分不再保留
+	if s.offset > 0 && s.offset < s.size {
+		var rest = make([]byte, s.size-s.offset)
+		if _, err := s.file.ReadAt(rest, s.offset); err == nil {
+			_ = s.file.Truncate(0)
+			_, _ = s.file.WriteAt(rest, 0)
+		}
+	}
+
+	_ = s.file.Close()
+}
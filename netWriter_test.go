@@ -0,0 +1,206 @@
+package onelog
+
+import (
+	"bufio"
+	"net"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestNetWriterSpool(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	var address = ln.Addr().String()
+	_ = ln.Close()
+
+	//服务端未启动时记录存入磁盘队列
+	nw, err := NewNetWriter("tcp", address, FramingNewline, filepath.Join(t.TempDir(), "spool"), 1024*1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nw.SetMaxBackoff(50 * time.Millisecond)
+	defer nw.Close()
+
+	for i := 0; i < 5; i++ {
+		_, _ = nw.Write([]byte("line" + strconv.Itoa(i) + "\n"))
+	}
+
+	if ln, err = net.Listen("tcp", address); err != nil {
+		t.Skip(err)
+	}
+	defer ln.Close()
+
+	var lines = make(chan string, 10)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		s := bufio.NewScanner(conn)
+		for s.Scan() {
+			lines <- s.Text()
+		}
+	}()
+
+	//重连后发送的记录排在队列之后
+	time.Sleep(200 * time.Millisecond)
+	_, _ = nw.Write([]byte("line5\n"))
+
+	for i := 0; i < 6; i++ {
+		select {
+		case line := <-lines:
+			if line != "line"+strconv.Itoa(i) {
+				t.Fatalf("顺序错误，预期line%d，实际%s", i, line)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("未收到第%d条记录", i)
+		}
+	}
+
+	if nw.Dropped() != 0 {
+		t.Errorf("不应丢弃记录，实际丢弃%d条", nw.Dropped())
+	}
+}
+
+func TestNetWriterNoSpool(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	var address = ln.Addr().String()
+	_ = ln.Close()
+
+	nw, err := NewNetWriter("tcp", address, FramingLength, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nw.Close()
+
+	_, _ = nw.Write([]byte("lost"))
+	if nw.Dropped() != 1 {
+		t.Error("没有磁盘队列时断开期间的记录应被丢弃")
+	}
+	if _, ok := nw.SetConfig(map[string]interface{}{"Address": address}).(Initialized); !ok {
+		t.Error("再次设置应返回Initialized")
+	}
+}
+
+//blockConn 第一次发送时等待release后返回错误，之后的发送都记录下来
+type blockConn struct {
+	net.Conn
+	entered chan struct{}
+	release chan struct{}
+	first   bool
+	frames  []string
+}
+
+func (c *blockConn) Write(p []byte) (int, error) {
+	if !c.first {
+		c.first = true
+		close(c.entered)
+		<-c.release
+		return 0, Closed("blockConn")
+	}
+	c.frames = append(c.frames, string(p))
+	return len(p), nil
+}
+
+func (*blockConn) SetWriteDeadline(time.Time) error {
+	return nil
+}
+
+func (*blockConn) Close() error {
+	return nil
+}
+
+//TestNetWriterSendOrder 发送失败的记录存入队列之前，其他协程不能在同一连接上发送
+func TestNetWriterSendOrder(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	var address = ln.Addr().String()
+	_ = ln.Close()
+
+	nw, err := NewNetWriter("tcp", address, FramingNewline, filepath.Join(t.TempDir(), "spool"), 1024*1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nw.Close()
+
+	var conn = &blockConn{entered: make(chan struct{}), release: make(chan struct{})}
+	nw.mutex.Lock()
+	nw.conn = conn
+	nw.mutex.Unlock()
+
+	var done = make(chan struct{}, 2)
+	go func() {
+		_, _ = nw.Write([]byte("first\n"))
+		done <- struct{}{}
+	}()
+	<-conn.entered
+	go func() {
+		_, _ = nw.Write([]byte("second\n"))
+		done <- struct{}{}
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	close(conn.release)
+	<-done
+	<-done
+
+	if len(conn.frames) != 0 {
+		t.Fatalf("发送失败后不应继续使用此连接:%q", conn.frames)
+	}
+
+	nw.mutex.Lock()
+	defer nw.mutex.Unlock()
+	for _, want := range []string{"first\n", "second\n"} {
+		p, err := nw.spool.peek()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(p) != want {
+			t.Fatalf("队列顺序错误，预期%q，实际%q", want, p)
+		}
+		nw.spool.pop(len(p))
+	}
+}
+
+func TestSpoolPartialTail(t *testing.T) {
+	var path = filepath.Join(t.TempDir(), "spool")
+	s, err := openSpool(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.push([]byte("first"))
+	s.push([]byte("second"))
+	var full = s.size
+	//模拟异常退出时只写入了一部分的记录
+	_, _ = s.file.WriteAt([]byte{0, 0, 0, 9, 'x'}, s.size)
+	_ = s.file.Close()
+
+	if s, err = openSpool(path, 0); err != nil {
+		t.Fatal(err)
+	}
+	defer s.close()
+	if s.size != full {
+		t.Fatalf("size %d, want %d", s.size, full)
+	}
+
+	for _, want := range []string{"first", "second"} {
+		p, err := s.peek()
+		if err != nil || string(p) != want {
+			t.Fatalf("peek %q %v, want %q", p, err, want)
+		}
+		s.pop(len(p))
+	}
+	if !s.empty() {
+		t.Fatal("spool not empty")
+	}
+}
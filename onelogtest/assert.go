@@ -0,0 +1,131 @@
+package onelogtest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/udbjqrmna/onelog"
+)
+
+//Matcher 检查一条记录是否满足条件
+type Matcher struct {
+	desc  string
+	match func(Entry) bool
+}
+
+func (m Matcher) String() string {
+	return m.desc
+}
+
+//Level 记录的等级为level
+func Level(level onelog.Level) Matcher {
+	return Matcher{"level=" + level.String(), func(e Entry) bool {
+		return e.Level == level
+	}}
+}
+
+//Message 记录的消息为msg
+func Message(msg string) Matcher {
+	return Matcher{"msg=" + msg, func(e Entry) bool {
+		return e.Message == msg
+	}}
+}
+
+//MessageContains 记录的消息包含s
+func MessageContains(s string) Matcher {
+	return Matcher{"msg contains " + s, func(e Entry) bool {
+		return strings.Contains(e.Message, s)
+	}}
+}
+
+//Field 记录中包含key项，且值的文本与value相同，如Field("user_id", 42)
+func Field(key string, value interface{}) Matcher {
+	var want = fmt.Sprint(value)
+
+	return Matcher{key + "=" + want, func(e Entry) bool {
+		v, ok := e.Fields[key]
+		return ok && fmt.Sprint(v) == want
+	}}
+}
+
+//HasField 记录中包含key项
+func HasField(key string) Matcher {
+	return Matcher{"has " + key, func(e Entry) bool {
+		_, ok := e.Fields[key]
+		return ok
+	}}
+}
+
+func matchAll(e Entry, matchers []Matcher) bool {
+	for _, m := range matchers {
+		if !m.match(e) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func describe(matchers []Matcher) string {
+	var desc = make([]string, len(matchers))
+	for i, m := range matchers {
+		desc[i] = m.desc
+	}
+
+	return strings.Join(desc, ", ")
+}
+
+//ExpectCount 检查满足所有条件的记录条数为n
+func (r *Recorder) ExpectCount(t testing.TB, n int, matchers ...Matcher) []Entry {
+	t.Helper()
+
+	var entries = r.Filter(matchers...)
+	if len(entries) != n {
+		t.Errorf("预期%d条满足[%s]的记录，实际%d条\n%s", n, describe(matchers), len(entries), r.dump())
+	}
+
+	return entries
+}
+
+//ExpectOne 检查满足所有条件的记录只有一条，如 ExpectOne(t, Level(onelog.ErrorLevel), Field("user_id", 42))
+func (r *Recorder) ExpectOne(t testing.TB, matchers ...Matcher) Entry {
+	t.Helper()
+
+	var entries = r.ExpectCount(t, 1, matchers...)
+	if len(entries) == 0 {
+		return Entry{}
+	}
+
+	return entries[0]
+}
+
+//ExpectNone 检查没有满足所有条件的记录
+func (r *Recorder) ExpectNone(t testing.TB, matchers ...Matcher) {
+	t.Helper()
+
+	r.ExpectCount(t, 0, matchers...)
+}
+
+//ExpectNoneAbove 检查没有高于level等级的记录
+func (r *Recorder) ExpectNoneAbove(t testing.TB, level onelog.Level) {
+	t.Helper()
+
+	for _, e := range r.Entries() {
+		if e.Level > level && e.Level != onelog.Disable {
+			t.Errorf("不应有高于%s的记录:%s", level, e.Raw)
+		}
+	}
+}
+
+//dump 生成所有记录的文本，用于错误信息
+func (r *Recorder) dump() string {
+	var b strings.Builder
+	for _, e := range r.Entries() {
+		b.WriteString("\t")
+		b.WriteString(e.Raw)
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
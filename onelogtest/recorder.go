@@ -0,0 +1,218 @@
+package onelogtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"github.com/udbjqrmna/onelog"
+)
+
+//Entry 解析后的一条日志记录
+type Entry struct {
+	Level   onelog.Level
+	Message string
+	//Fields 记录中的所有项，包含等级、时间与消息
+	Fields map[string]interface{}
+	Raw    string
+}
+
+//Recorder 保存所有写入的记录，用于在测试中检查日志。实现了onelog.Writer
+type Recorder struct {
+	entries []Entry
+	mutex   sync.Mutex
+}
+
+//NewRecorder 返回一个新的Recorder
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+//New 返回一个写入至Recorder的日志对象，使用JsonPattern
+func New(level onelog.Level) (*onelog.Logger, *Recorder) {
+	var r = NewRecorder()
+
+	return onelog.New(r, level, &onelog.JsonPattern{}), r
+}
+
+func (r *Recorder) Write(p []byte) (n int, err error) {
+	return r.WriteLevel(onelog.Disable, p)
+}
+
+//WriteLevel 解析并保存一条记录，等级未知时使用记录中的等级项
+func (r *Recorder) WriteLevel(level onelog.Level, p []byte) (n int, err error) {
+	var entry = Parse(p)
+	if level != onelog.Disable {
+		entry.Level = level
+	}
+
+	r.mutex.Lock()
+	r.entries = append(r.entries, entry)
+	r.mutex.Unlock()
+
+	return len(p), nil
+}
+
+func (*Recorder) Close() {
+}
+
+func (*Recorder) Flush() {
+}
+
+func (*Recorder) SetConfig(config interface{}) error {
+	return nil
+}
+
+//Entries 返回目前所有的记录
+func (r *Recorder) Entries() []Entry {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return append([]Entry(nil), r.entries...)
+}
+
+//Filter 返回满足所有条件的记录
+func (r *Recorder) Filter(matchers ...Matcher) []Entry {
+	var result []Entry
+
+	for _, e := range r.Entries() {
+		if matchAll(e, matchers) {
+			result = append(result, e)
+		}
+	}
+
+	return result
+}
+
+//Reset 清除目前所有的记录
+func (r *Recorder) Reset() {
+	r.mutex.Lock()
+	r.entries = nil
+	r.mutex.Unlock()
+}
+
+//Parse 将一条JsonPattern或OldPattern生成的记录解析为Entry
+func Parse(record []byte) Entry {
+	var raw = string(bytes.TrimRight(record, "\r\n"))
+	var entry = Entry{
+		Level:  onelog.Disable,
+		Fields: make(map[string]interface{}),
+		Raw:    raw,
+	}
+
+	if strings.HasPrefix(raw, "{") {
+		parseJSON(raw, entry.Fields)
+	} else {
+		//OldPattern：时间在最前，其后为以\t分隔的 key:value
+		var parts = strings.Split(raw, "\t")
+		if len(parts) > 0 && !strings.Contains(parts[0], ":") {
+			entry.Fields[onelog.TimeName] = parts[0]
+		}
+		for _, part := range parts {
+			if i := strings.IndexByte(part, ':'); i > 0 {
+				entry.Fields[part[:i]] = part[i+1:]
+			}
+		}
+	}
+
+	if msg, ok := entry.Fields[onelog.MessageName].(string); ok {
+		entry.Message = msg
+	}
+	if level, ok := entry.Fields[onelog.LevelName].(string); ok {
+		entry.Level = parseLevel(level)
+	}
+
+	return entry
+}
+
+//parseJSON 解析JsonPattern生成的记录。错误与时间等项的值未加引号，无法直接使用json.Unmarshal，
+//此类值取至下一个key之前
+func parseJSON(raw string, fields map[string]interface{}) {
+	var s = strings.TrimSuffix(strings.TrimPrefix(raw, "{"), "}")
+
+	for len(s) > 0 && s[0] == '"' {
+		end := quoteEnd(s)
+		if end < 0 || end+1 >= len(s) || s[end+1] != ':' {
+			return
+		}
+		var key string
+		if json.Unmarshal([]byte(s[:end+1]), &key) != nil {
+			return
+		}
+		s = s[end+2:]
+
+		var value interface{}
+		if len(s) > 0 && s[0] == '"' {
+			if end = quoteEnd(s); end < 0 {
+				return
+			}
+			var str string
+			_ = json.Unmarshal([]byte(s[:end+1]), &str)
+			value = str
+			end++
+		} else {
+			end = rawEnd(s)
+			value = rawValue(s[:end])
+		}
+
+		fields[key] = value
+		s = strings.TrimPrefix(s[end:], ",")
+	}
+}
+
+//quoteEnd 返回s开头的带引号字符串结束引号的位置
+func quoteEnd(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+
+	return -1
+}
+
+//rawEnd 返回未加引号的值结束的位置，即下一个 ,"key": 之前
+func rawEnd(s string) int {
+	for i := 0; i < len(s); i++ {
+		if s[i] != ',' || i+1 >= len(s) || s[i+1] != '"' {
+			continue
+		}
+		if end := quoteEnd(s[i+1:]); end > 0 && i+end+2 < len(s) && s[i+end+2] == ':' {
+			return i
+		}
+	}
+
+	return len(s)
+}
+
+func rawValue(s string) interface{} {
+	switch s {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "null":
+		return nil
+	}
+
+	var n json.Number
+	if json.Unmarshal([]byte(s), &n) == nil {
+		return n
+	}
+
+	return s
+}
+
+func parseLevel(s string) onelog.Level {
+	for l := onelog.TraceLevel; l <= onelog.PanicLevel; l++ {
+		if strings.EqualFold(l.String(), s) {
+			return l
+		}
+	}
+
+	return onelog.Disable
+}
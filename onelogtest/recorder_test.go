@@ -0,0 +1,70 @@
+package onelogtest
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/udbjqrmna/onelog"
+)
+
+func TestRecorder(t *testing.T) {
+	log, rec := New(onelog.DebugLevel)
+
+	log.Debug().Msg("start")
+	log.Error().Int("user_id", 42).Error(errors.New("boom")).Msg("failed")
+	log.Warn().String("user_id", "7").Msg("slow")
+
+	var e = rec.ExpectOne(t, Level(onelog.ErrorLevel), Field("user_id", 42))
+	if e.Message != "failed" || e.Fields[onelog.ErrorName] != "boom" {
+		t.Error(e)
+	}
+
+	rec.ExpectCount(t, 2, HasField("user_id"))
+	rec.ExpectNone(t, Level(onelog.InfoLevel))
+	rec.ExpectNoneAbove(t, onelog.ErrorLevel)
+
+	rec.Reset()
+	rec.ExpectCount(t, 0)
+}
+
+func TestParseOldPattern(t *testing.T) {
+	var e = Parse([]byte("2021-01-01T00:00:00Z\tlevel:WARN\tk:v\tmsg:hello\n"))
+
+	if e.Level != onelog.WarnLevel || e.Message != "hello" || e.Fields["k"] != "v" {
+		t.Errorf("%+v", e)
+	}
+}
+
+//fakeTB 记录Log的内容，其它方法使用嵌入的testing.TB
+type fakeTB struct {
+	testing.TB
+	lines []string
+}
+
+func (f *fakeTB) Helper() {
+}
+
+func (f *fakeTB) Log(args ...interface{}) {
+	f.lines = append(f.lines, fmt.Sprint(args...))
+}
+
+func TestTBWriter(t *testing.T) {
+	var tb = &fakeTB{TB: t}
+	var log = NewTestLogger(tb, onelog.InfoLevel)
+	log.Info().Int("i", 1).Msg("routed to t.Log")
+	log.Debug().Msg("filtered")
+
+	if len(tb.lines) != 1 {
+		t.Fatal(tb.lines)
+	}
+
+	var e = Parse([]byte(tb.lines[0]))
+	if e.Level != onelog.InfoLevel || e.Message != "routed to t.Log" || e.Fields["i"] != "1" {
+		t.Errorf("%q %+v", tb.lines[0], e)
+	}
+	if strings.HasSuffix(tb.lines[0], "\n") {
+		t.Errorf("%q", tb.lines[0])
+	}
+}
@@ -0,0 +1,40 @@
+package onelogtest
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/udbjqrmna/onelog"
+)
+
+//TBWriter 将记录写入testing.TB的Log，输出将归属于对应的测试
+type TBWriter struct {
+	tb testing.TB
+}
+
+//NewTBWriter 返回一个写入至t.Log的Writer
+func NewTBWriter(t testing.TB) *TBWriter {
+	return &TBWriter{t}
+}
+
+//NewTestLogger 返回一个写入至t.Log的日志对象，使用OldPattern便于阅读
+func NewTestLogger(t testing.TB, level onelog.Level) *onelog.Logger {
+	return onelog.New(NewTBWriter(t), level, &onelog.OldPattern{})
+}
+
+func (w *TBWriter) Write(p []byte) (n int, err error) {
+	w.tb.Helper()
+	w.tb.Log(string(bytes.TrimRight(p, "\r\n")))
+
+	return len(p), nil
+}
+
+func (*TBWriter) Close() {
+}
+
+func (*TBWriter) Flush() {
+}
+
+func (*TBWriter) SetConfig(config interface{}) error {
+	return nil
+}
@@ -0,0 +1,159 @@
+package onelog
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//RateLimiter 令牌桶限流，每秒补充Rate个令牌，最多积累Burst个。没有令牌时按Overflow处理：
+//OverflowDropNewest丢弃，OverflowBlock等待令牌，OverflowBlockTimeout最多等待Timeout，
+//OverflowDropBelowLevel丢弃低于DropLevel的记录，其他记录不受限制。Overflow的零值为OverflowBlock
+type RateLimiter struct {
+	Rate      float64
+	Burst     int
+	Overflow  OverflowPolicy
+	Timeout   time.Duration
+	DropLevel Level
+	tokens    float64
+	last      time.Time
+	mutex     sync.Mutex
+}
+
+//SetRateLimit 设置Logger的限流，被限流丢弃的记录与采样一样计入Sampled与汇总记录。limiter为nil时取消限流
+func (l *Logger) SetRateLimit(limiter *RateLimiter) *Logger {
+	if l.base != nil {
+		l.base.SetRateLimit(limiter)
+		return l
+	}
+
+	l.sampling.update(func(r *samplingRules) {
+		r.limiter = limiter
+	})
+
+	return l
+}
+
+//Allow 返回此等级的记录是否可以写入
+func (r *RateLimiter) Allow(level Level) bool {
+	var wait, ok = r.take()
+	if ok {
+		return true
+	}
+
+	switch r.Overflow {
+	case OverflowBlock:
+		for !ok {
+			time.Sleep(wait)
+			wait, ok = r.take()
+		}
+		return true
+	case OverflowBlockTimeout:
+		var deadline = time.Now().Add(r.Timeout)
+		for !ok {
+			if time.Now().Add(wait).After(deadline) {
+				return false
+			}
+			time.Sleep(wait)
+			wait, ok = r.take()
+		}
+		return true
+	case OverflowDropBelowLevel:
+		return level >= r.DropLevel
+	}
+
+	return false
+}
+
+//take 取出一个令牌，没有令牌时返回需要等待的时间
+func (r *RateLimiter) take() (time.Duration, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var now = time.Now()
+	if r.last.IsZero() {
+		r.tokens = float64(r.burst())
+	} else {
+		r.tokens += now.Sub(r.last).Seconds() * r.Rate
+		if max := float64(r.burst()); r.tokens > max {
+			r.tokens = max
+		}
+	}
+	r.last = now
+
+	if r.tokens >= 1 {
+		r.tokens--
+		return 0, true
+	}
+	if r.Rate <= 0 {
+		return time.Second, false
+	}
+
+	return time.Duration((1 - r.tokens) / r.Rate * float64(time.Second)), false
+}
+
+func (r *RateLimiter) burst() int {
+	if r.Burst <= 0 {
+		return 1
+	}
+
+	return r.Burst
+}
+
+//loadRateLimit 按配置中的RateLimit设置Logger的限流
+func loadRateLimit(log *Logger, config map[string]interface{}) error {
+	val, ok := config["RateLimit"]
+	if !ok {
+		return nil
+	}
+
+	conf, ok := val.(map[string]interface{})
+	if !ok {
+		return &MistakeType{"json type", "RateLimit"}
+	}
+
+	var nums = map[string]float64{"Rate": 0, "Burst": 0, "Timeout": 100}
+	for key := range nums {
+		if val, ok := conf[key]; ok {
+			switch val.(type) {
+			case float64:
+				if val.(float64) < 0 {
+					return &MistakeType{"大于等于0", strconv.FormatFloat(val.(float64), 'f', -1, 64)}
+				}
+				nums[key] = val.(float64)
+			default:
+				return &MistakeType{"number type", key}
+			}
+		}
+	}
+	if nums["Rate"] <= 0 {
+		return NotNil("RateLimit:Rate")
+	}
+
+	var limiter = &RateLimiter{
+		Rate:      nums["Rate"],
+		Burst:     int(nums["Burst"]),
+		Overflow:  OverflowDropNewest,
+		Timeout:   time.Duration(nums["Timeout"]) * time.Millisecond,
+		DropLevel: ErrorLevel,
+	}
+
+	if val, ok := conf["Overflow"]; ok {
+		name, _ := val.(string)
+		if limiter.Overflow, ok = refOverflow[strings.ToLower(name)]; !ok || limiter.Overflow == OverflowDropOldest {
+			return NotUnderstand("Overflow:" + name)
+		}
+	}
+
+	if val, ok := conf["DropLevel"]; ok {
+		var err error
+		if limiter.DropLevel, err = parseLevel(val); err != nil {
+			return err
+		}
+	}
+
+	log.SetRateLimit(limiter)
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package onelog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	var r = &RateLimiter{Rate: 1, Burst: 5, Overflow: OverflowDropNewest}
+	var kept = 0
+	for i := 0; i < 100; i++ {
+		if r.Allow(InfoLevel) {
+			kept++
+		}
+	}
+	if kept != 5 {
+		t.Errorf("应只允许Burst条，实际%d条", kept)
+	}
+
+	r = &RateLimiter{Rate: 1, Burst: 1, Overflow: OverflowDropBelowLevel, DropLevel: ErrorLevel}
+	r.Allow(InfoLevel)
+	if r.Allow(WarnLevel) || !r.Allow(ErrorLevel) {
+		t.Error("DropLevel以上的记录不应被丢弃")
+	}
+
+	r = &RateLimiter{Rate: 100, Burst: 1, Overflow: OverflowBlock}
+	var start = time.Now()
+	for i := 0; i < 3; i++ {
+		r.Allow(InfoLevel)
+	}
+	if time.Since(start) < 15*time.Millisecond {
+		t.Error("没有令牌时应等待")
+	}
+
+	r = &RateLimiter{Rate: 1, Burst: 1, Overflow: OverflowBlockTimeout, Timeout: 10 * time.Millisecond}
+	r.Allow(InfoLevel)
+	if r.Allow(InfoLevel) {
+		t.Error("超时后应丢弃")
+	}
+}
+
+func TestLoggerRateLimit(t *testing.T) {
+	var buf bytes.Buffer
+	var log = New(&Stdout{&buf}, DebugLevel, &JsonPattern{})
+	log.SetRateLimit(&RateLimiter{Rate: 1, Burst: 10, Overflow: OverflowDropNewest})
+
+	for i := 0; i < 50; i++ {
+		log.Info().Msg("flood")
+	}
+
+	if n := strings.Count(buf.String(), "flood"); n != 10 {
+		t.Errorf("应写入10条，实际%d条", n)
+	}
+	if log.Sampled(InfoLevel) != 40 {
+		t.Errorf("丢弃条数不正确:%d", log.Sampled(InfoLevel))
+	}
+
+	err := loadRateLimit(log, map[string]interface{}{
+		"RateLimit": map[string]interface{}{"Rate": float64(100), "Burst": float64(20), "Overflow": "drop-below-level", "DropLevel": "warn"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := log.sampling.load().limiter; l.Rate != 100 || l.Burst != 20 || l.Overflow != OverflowDropBelowLevel || l.DropLevel != WarnLevel {
+		t.Errorf("%#v", l)
+	}
+	if err := loadRateLimit(log, map[string]interface{}{"RateLimit": map[string]interface{}{"Rate": float64(1), "Overflow": "drop-oldest"}}); err == nil {
+		t.Error("drop-oldest不适用于限流")
+	}
+}
@@ -0,0 +1,165 @@
+package onelog
+
+import (
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+//SetActiveName 设置当前日志文件名的模板，如"app.{yyyy}{MM}{dd}.log"，文件与FileName在同一目录。
+//可使用的占位符与SetArchiveName相同，时间为文件创建的时间，切分后按新的时间创建文件。
+//一般与SetCurrentLink同时使用，不能与多进程模式同时使用。template为空时使用FileName
+func (w *FileWriter) SetActiveName(template string) error {
+	if strings.ContainsAny(template, `/\`) {
+		return NotUnderstand("ActiveName:" + template)
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if template != "" && w.lock != nil {
+		return NotUnderstand("MultiProcess:ActiveName")
+	}
+	if w.naming.hostname == "" {
+		w.naming.hostname, _ = os.Hostname()
+	}
+	w.activeName = template
+
+	if w.closed || w.active == "" {
+		return Closed("FileWriter")
+	}
+	w.writeBuffer()
+	w.openActive()
+
+	return nil
+}
+
+//activePath 按模板与当前时间生成日志文件的路径，调用方需要持有锁
+func (w *FileWriter) activePath() string {
+	if w.activeName == "" {
+		return w.fileName
+	}
+
+	var t = time.Now()
+	var label = t.Format("20060102")
+	if w.schedule != nil {
+		t = t.In(scheduleLocation(w.schedule))
+		label = w.schedule.Label(t)
+	}
+
+	var naming = archiveNaming{template: w.activeName, hostname: w.naming.hostname}
+	return naming.path(w.fileName, t, label, "", "")
+}
+
+//openActive 当前文件名需要变化时打开新的文件，旧文件为空时删除，调用方需要持有锁
+func (w *FileWriter) openActive() {
+	var path = w.activePath()
+	if path == w.active {
+		return
+	}
+
+	f, err := createLogWriteFile(path)
+	if err != nil {
+		w.report("FileWriter", "open", path, err)
+		return
+	}
+
+	if info, e := w.file.Stat(); e == nil && info.Size() == 0 {
+		_ = os.Remove(w.active)
+	}
+	_ = w.file.Close()
+	w.file = f
+	w.active = path
+	w.report("FileWriter", "link", w.link, w.updateLink())
+}
+
+//SetCurrentLink 设置一个指向当前日志文件的符号链接，如app.current.log。切分与重新打开后将更新此链接，link为空时删除原有的链接
+func (w *FileWriter) SetCurrentLink(link string) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if link == "" && w.link != "" {
+		_ = os.Remove(w.link)
+	}
+	w.link = link
+
+	return w.updateLink()
+}
+
+//updateLink 重新创建指向当前日志文件的符号链接，调用方需要持有锁
+func (w *FileWriter) updateLink() error {
+	if w.link == "" {
+		return nil
+	}
+
+	//同一目录时使用相对路径，目录整体移动后链接仍然有效
+	var target = w.active
+	if rel, err := filepath.Rel(filepath.Dir(w.link), w.active); err == nil {
+		target = rel
+	}
+	if current, err := os.Readlink(w.link); err == nil && current == target {
+		return nil
+	}
+
+	//先创建临时链接再改名，链接在任何时刻都存在
+	var temp = w.link + ".tmp"
+	_ = os.Remove(temp)
+	if err := os.Symlink(target, temp); err != nil {
+		return err
+	}
+
+	return os.Rename(temp, w.link)
+}
+
+//Reopen 将缓存写入当前文件后关闭，并重新打开日志文件。配合logrotate的create方式使用：logrotate改名后调用此方法
+func (w *FileWriter) Reopen() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.closed || w.active == "" {
+		return Closed("FileWriter")
+	}
+
+	w.writeBuffer()
+
+	var path = w.activePath()
+	f, err := createLogWriteFile(path)
+	if err != nil {
+		return err
+	}
+	_ = w.file.Close()
+	w.file = f
+	w.active = path
+
+	return w.updateLink()
+}
+
+//ReopenOnSignal 收到指定的信号时调用Reopen，一般为SIGHUP
+func (w *FileWriter) ReopenOnSignal(sig ...os.Signal) *FileWriter {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.stopReopenSignal()
+
+	w.signals = make(chan os.Signal, 1)
+	signal.Notify(w.signals, sig...)
+
+	go func(signals chan os.Signal) {
+		for range signals {
+			w.report("FileWriter", "reopen", w.fileName, w.Reopen())
+		}
+	}(w.signals)
+
+	return w
+}
+
+//stopReopenSignal 停止接收信号，调用方需要持有锁
+func (w *FileWriter) stopReopenSignal() {
+	if w.signals != nil {
+		signal.Stop(w.signals)
+		close(w.signals)
+		w.signals = nil
+	}
+}
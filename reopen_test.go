@@ -0,0 +1,126 @@
+//go:build !windows
+// +build !windows
+
+package onelog
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestCurrentLinkAndReopen(t *testing.T) {
+	var dir = t.TempDir()
+	var w = &FileWriter{}
+	err := w.SetConfig(map[string]interface{}{
+		"LogsRoot":     dir,
+		"FileName":     "app.log",
+		"MaxCapacity":  float64(1),
+		"CurrentLink":  "app.current.log",
+		"ReopenSignal": "SIGHUP",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	var link = filepath.Join(dir, "app.current.log")
+	if target, err := os.Readlink(link); err != nil || target != "app.log" {
+		t.Fatalf("链接不正确:%s %v", target, err)
+	}
+
+	_, _ = w.Write([]byte("before\n"))
+
+	//模拟logrotate的create方式：改名后通知重新打开
+	var name = filepath.Join(dir, "app.log")
+	_ = os.Rename(name, name+".1")
+	if err = w.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	_, _ = w.Write([]byte("after\n"))
+	w.Flush()
+
+	if data, _ := ioutil.ReadFile(name + ".1"); string(data) != "before\n" {
+		t.Errorf("旧文件内容不正确:%q", data)
+	}
+	if data, _ := ioutil.ReadFile(link); string(data) != "after\n" {
+		t.Errorf("新文件内容不正确:%q", data)
+	}
+
+	_ = os.Rename(name, name+".2")
+	_ = syscall.Kill(os.Getpid(), syscall.SIGHUP)
+	for i := 0; i < 100 && !exists(name); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !exists(name) {
+		t.Error("收到SIGHUP后未重新打开文件")
+	}
+}
+
+func TestActiveNameLink(t *testing.T) {
+	var dir = t.TempDir()
+	var w = &FileWriter{}
+	err := w.SetConfig(map[string]interface{}{
+		"LogsRoot":    dir,
+		"FileName":    "app.log",
+		"MaxCapacity": float64(1),
+		"ActiveName":  "app.{yyyy}{MM}{dd}.log",
+		"CurrentLink": "app.current.log",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	var name = time.Now().Format("app.20060102.log")
+	if target, err := os.Readlink(filepath.Join(dir, "app.current.log")); err != nil || target != name {
+		t.Fatalf("链接应指向带日期的文件:%s %v", target, err)
+	}
+	if exists(filepath.Join(dir, "app.log")) {
+		t.Error("空的app.log应被删除")
+	}
+
+	_, _ = w.Write([]byte("line\n"))
+	w.Flush()
+	if data, _ := ioutil.ReadFile(filepath.Join(dir, name)); string(data) != "line\n" {
+		t.Errorf("内容不正确:%q", data)
+	}
+
+	if err = w.SetMultiProcess(true); err == nil {
+		t.Error("ActiveName不能与多进程模式同时使用")
+	}
+}
+
+func TestActiveNameRotate(t *testing.T) {
+	var dir = t.TempDir()
+	fw, err := NewFileWriter(filepath.Join(dir, "app.log"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.SetCodec(NoCompression{})
+	fw.SetRotateSchedule(everySchedule(30 * time.Millisecond))
+	if err = fw.SetActiveName("app.{label}.log"); err != nil {
+		t.Fatal(err)
+	}
+	if err = fw.SetCurrentLink(filepath.Join(dir, "current")); err != nil {
+		t.Fatal(err)
+	}
+	defer fw.Close()
+
+	var before, _ = os.Readlink(filepath.Join(dir, "current"))
+	_, _ = fw.Write([]byte("a\n"))
+	time.Sleep(40 * time.Millisecond)
+	_, _ = fw.Write([]byte("b\n"))
+	fw.Flush()
+
+	var after, _ = os.Readlink(filepath.Join(dir, "current"))
+	if after == before || !exists(filepath.Join(dir, after)) {
+		t.Errorf("切分后链接应指向新的文件:%s %s", before, after)
+	}
+	if data, _ := ioutil.ReadFile(filepath.Join(dir, after)); string(data) != "b\n" {
+		t.Errorf("新文件内容不正确:%q", data)
+	}
+}
@@ -0,0 +1,79 @@
+package onelog
+
+import (
+	"os"
+	"sort"
+	"time"
+)
+
+//RetentionPolicy 归档文件的保留策略，值为0的项不做限制
+type RetentionPolicy struct {
+	//MaxArchives 最多保留的归档文件个数
+	MaxArchives int
+	//MaxAge 归档文件最长的保留时间
+	MaxAge time.Duration
+	//MaxTotalSize 所有归档文件合计的最大字节数
+	MaxTotalSize int64
+	//OnRemove 删除归档文件后的回调，reason为删除原因：count、age或size，err为删除时出现的错误
+	OnRemove func(path string, reason string, err error)
+}
+
+type archiveFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+//SetRetention 设置归档文件的保留策略，设置时将立即按策略清理一次已存在的归档文件
+func (w *FileWriter) SetRetention(policy *RetentionPolicy) *FileWriter {
+	w.mutex.Lock()
+	w.retention = policy
+	var codec, naming = w.codec, w.naming
+	w.mutex.Unlock()
+
+	w.enforceRetention(policy, codec, naming)
+
+	return w
+}
+
+//enforceRetention 扫描归档文件所在目录，按保留策略删除多余的归档文件
+func (w *FileWriter) enforceRetention(policy *RetentionPolicy, codec Codec, naming archiveNaming) {
+	if policy == nil {
+		return
+	}
+
+	//同一时间只进行一次清理
+	w.retentionMutex.Lock()
+	defer w.retentionMutex.Unlock()
+
+	var archives = listArchives(w.fileName, codec, naming)
+	//按修改时间从新到旧排列
+	sort.Slice(archives, func(i, j int) bool {
+		return archives[i].modTime.After(archives[j].modTime)
+	})
+
+	var now = time.Now()
+	var total int64
+
+	for i, a := range archives {
+		var reason string
+
+		switch {
+		case policy.MaxArchives > 0 && i >= policy.MaxArchives:
+			reason = "count"
+		case policy.MaxAge > 0 && now.Sub(a.modTime) > policy.MaxAge:
+			reason = "age"
+		case policy.MaxTotalSize > 0 && total+a.size > policy.MaxTotalSize:
+			reason = "size"
+		default:
+			total += a.size
+			continue
+		}
+
+		var err = os.Remove(a.path)
+		w.report("FileWriter", "remove", a.path, err)
+		if policy.OnRemove != nil {
+			policy.OnRemove(a.path, reason, err)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package onelog
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRetention(t *testing.T) {
+	dir := t.TempDir()
+	var name = filepath.Join(dir, "app.log")
+
+	//生成10个归档文件，每个相隔一天，每个100字节
+	var now = time.Now()
+	for i := 0; i < 10; i++ {
+		var archive = name + ".0101_" + string(rune('0'+i)) + ".gz"
+		_ = ioutil.WriteFile(archive, make([]byte, 100), 0666)
+		var mod = now.Add(-time.Duration(i) * 24 * time.Hour)
+		_ = os.Chtimes(archive, mod, mod)
+	}
+	//不属于此日志的文件不能被删除
+	_ = ioutil.WriteFile(filepath.Join(dir, "other.log.0101_1.gz"), nil, 0666)
+
+	fw, err := NewFileWriter(name, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fw.Close()
+
+	var reasons = make(map[string]int)
+	fw.SetRetention(&RetentionPolicy{
+		MaxArchives: 8,
+		MaxAge:      6*24*time.Hour + time.Hour,
+		OnRemove: func(path string, reason string, err error) {
+			if err != nil {
+				t.Error(err)
+			}
+			reasons[reason]++
+		},
+	})
+
+	if reasons["count"] != 2 || reasons["age"] != 1 {
+		t.Errorf("删除原因不正确:%v", reasons)
+	}
+
+	fw.SetRetention(&RetentionPolicy{MaxTotalSize: 450})
+	if archives := listArchives(name, refCodec["gzip"], archiveNaming{}); len(archives) != 4 {
+		t.Errorf("预期保留4个归档文件，实际%d个", len(archives))
+	}
+	if !exists(filepath.Join(dir, "other.log.0101_1.gz")) {
+		t.Error("其他日志的归档文件被删除")
+	}
+}
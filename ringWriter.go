@@ -0,0 +1,285 @@
+package onelog
+
+import (
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type ringRecord struct {
+	level Level
+	data  []byte
+}
+
+//RingWriter 在内存中保留最近的记录，需要时可将其写入另一个Writer。写入时重用记录的空间，只持有很短时间的锁。
+//可配合LevelFilter使用：日志对象使用较低的等级，其他Writer只写入较高的等级，此Writer保留全部等级的记录
+type RingWriter struct {
+	records    []ringRecord
+	head       int
+	count      int
+	size       int
+	maxRecords int
+	maxBytes   int
+	minLevel   Level
+	dumpLevel  Level
+	dumpTo     Writer
+	ownDump    bool
+	signals    chan os.Signal
+	mutex      sync.Mutex
+	dumpMutex  sync.Mutex
+}
+
+//NewRingWriter 返回一个新的RingWriter，最多保留maxRecords条记录，maxBytes大于0时同时限制保留的字节数
+func NewRingWriter(maxRecords, maxBytes int) *RingWriter {
+	var r = &RingWriter{}
+	r.init(maxRecords, maxBytes)
+
+	return r
+}
+
+func (r *RingWriter) init(maxRecords, maxBytes int) {
+	if maxRecords <= 0 && maxBytes <= 0 {
+		maxRecords = 1000
+	}
+
+	var capacity = maxRecords
+	if capacity <= 0 {
+		capacity = 64
+	}
+
+	r.records = make([]ringRecord, capacity)
+	r.maxRecords = maxRecords
+	r.maxBytes = maxBytes
+	r.minLevel = TraceLevel
+	r.dumpLevel = Disable
+}
+
+//SetMinLevel 设置保留记录的最低等级
+func (r *RingWriter) SetMinLevel(level Level) *RingWriter {
+	r.mutex.Lock()
+	r.minLevel = level
+	r.mutex.Unlock()
+
+	return r
+}
+
+//SetDumpTo 设置自动写出时的目标Writer，level为触发自动写出的等级，如FatalLevel。
+//level为Disable时不自动写出
+func (r *RingWriter) SetDumpTo(writer Writer, level Level) *RingWriter {
+	r.mutex.Lock()
+	r.dumpTo = writer
+	r.dumpLevel = level
+	r.ownDump = false
+	r.mutex.Unlock()
+
+	return r
+}
+
+//DumpOnSignal 收到指定的信号时将保留的记录写出至SetDumpTo设置的Writer
+func (r *RingWriter) DumpOnSignal(sig ...os.Signal) *RingWriter {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.signals != nil {
+		signal.Stop(r.signals)
+		close(r.signals)
+	}
+
+	r.signals = make(chan os.Signal, 1)
+	signal.Notify(r.signals, sig...)
+
+	go func(signals chan os.Signal) {
+		for range signals {
+			r.dump()
+		}
+	}(r.signals)
+
+	return r
+}
+
+func (r *RingWriter) Write(p []byte) (n int, err error) {
+	return r.WriteLevel(Disable, p)
+}
+
+//WriteLevel 保留一条记录，达到触发等级时将全部保留的记录写出
+func (r *RingWriter) WriteLevel(level Level, p []byte) (n int, err error) {
+	r.mutex.Lock()
+
+	if level < r.minLevel {
+		r.mutex.Unlock()
+		return len(p), nil
+	}
+
+	//放弃最早的记录，直到满足条数与字节数的限制
+	for r.count > 0 && ((r.maxRecords > 0 && r.count >= r.maxRecords) || (r.maxBytes > 0 && r.size+len(p) > r.maxBytes)) {
+		r.size -= len(r.records[r.head].data)
+		r.head = (r.head + 1) % len(r.records)
+		r.count--
+	}
+
+	//只限制字节数时按需扩大
+	if r.count == len(r.records) {
+		var records = make([]ringRecord, len(r.records)*2)
+		for i := 0; i < r.count; i++ {
+			records[i] = r.records[(r.head+i)%len(r.records)]
+		}
+		r.records = records
+		r.head = 0
+	}
+
+	//重用已放弃记录的空间
+	var slot = &r.records[(r.head+r.count)%len(r.records)]
+	slot.level = level
+	slot.data = append(slot.data[:0], p...)
+	r.count++
+	r.size += len(p)
+
+	var to Writer
+	if level >= r.dumpLevel && level != Disable {
+		to = r.dumpTo
+	}
+	r.mutex.Unlock()
+
+	if to != nil {
+		r.Dump(to)
+	}
+
+	return len(p), nil
+}
+
+//Records 返回当前保留的记录，从早到晚排列
+func (r *RingWriter) Records() [][]byte {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var result = make([][]byte, r.count)
+	for i := 0; i < r.count; i++ {
+		var rec = r.records[(r.head+i)%len(r.records)]
+		result[i] = append([]byte(nil), rec.data...)
+	}
+
+	return result
+}
+
+//Dump 将保留的记录从早到晚写入至writer，写入后的记录仍然保留
+func (r *RingWriter) Dump(writer Writer) {
+	r.mutex.Lock()
+	var records = make([]ringRecord, r.count)
+	for i := 0; i < r.count; i++ {
+		var rec = r.records[(r.head+i)%len(r.records)]
+		records[i] = ringRecord{rec.level, append([]byte(nil), rec.data...)}
+	}
+	r.mutex.Unlock()
+
+	//同时只进行一次写出，避免多次写出的内容交错
+	r.dumpMutex.Lock()
+	defer r.dumpMutex.Unlock()
+
+	for _, rec := range records {
+		_, _ = writeLevel(writer, rec.level, rec.data)
+	}
+	flush(writer)
+}
+
+//dump 写出至SetDumpTo设置的Writer，未设置时不做处理
+func (r *RingWriter) dump() {
+	r.mutex.Lock()
+	var to = r.dumpTo
+	r.mutex.Unlock()
+
+	if to != nil {
+		r.Dump(to)
+	}
+}
+
+func (*RingWriter) Flush() {
+}
+
+func (r *RingWriter) Close() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.signals != nil {
+		signal.Stop(r.signals)
+		close(r.signals)
+		r.signals = nil
+	}
+
+	if r.ownDump && r.dumpTo != nil {
+		r.dumpTo.Close()
+		r.dumpTo = nil
+	}
+}
+
+//SetConfig 设置相关参数
+func (r *RingWriter) SetConfig(config interface{}) error {
+	var conf, ok = config.(map[string]interface{})
+	if !ok {
+		return &MistakeType{"map[string]interface {} type", ""}
+	}
+
+	var nums = map[string]int{"MaxRecords": 0, "MaxBytes": 0}
+	for key := range nums {
+		if val, ok := conf[key]; ok {
+			switch val.(type) {
+			case float64:
+				v := int(val.(float64))
+				if v <= 0 {
+					return &MistakeType{"大于0", strconv.Itoa(v)}
+				}
+				nums[key] = v
+			default:
+				return &MistakeType{"number type", key}
+			}
+		}
+	}
+
+	var minLevel, dumpLevel = TraceLevel, FatalLevel
+	var err error
+	if val, ok := conf["MinLevel"]; ok {
+		if minLevel, err = parseLevel(val); err != nil {
+			return err
+		}
+	}
+	if val, ok := conf["DumpLevel"]; ok {
+		if dumpLevel, err = parseLevel(val); err != nil {
+			return err
+		}
+	}
+
+	var dumpTo Writer
+	if val, ok := conf["DumpTo"]; ok {
+		rec, ok := val.(map[string]interface{})
+		if !ok {
+			return &MistakeType{"json type", "DumpTo"}
+		}
+		if dumpTo, err = newWriterFromConfig(rec); err != nil {
+			return err
+		}
+	}
+
+	var signals []os.Signal
+	if val, ok := conf["DumpSignal"]; ok {
+		name, _ := val.(string)
+		sig, ok := refSignal[strings.ToUpper(name)]
+		if !ok {
+			return NotUnderstand("DumpSignal:" + name)
+		}
+		signals = append(signals, sig)
+	}
+
+	//MaxBytes以K为单位
+	r.init(nums["MaxRecords"], nums["MaxBytes"]*1024)
+	r.SetMinLevel(minLevel)
+	if dumpTo != nil {
+		r.SetDumpTo(dumpTo, dumpLevel)
+		r.ownDump = true
+	}
+	if len(signals) > 0 {
+		r.DumpOnSignal(signals...)
+	}
+
+	return nil
+}
@@ -0,0 +1,56 @@
+package onelog
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestRingWriter(t *testing.T) {
+	var out, dump bytes.Buffer
+	var ring = NewRingWriter(3, 0).SetDumpTo(&Stdout{Writer: &dump}, FatalLevel)
+
+	//日志对象使用DEBUG，其他Writer只写入WARN以上
+	var log = New(NewMultipleWriter(NewLevelFilter(&Stdout{Writer: &out}, WarnLevel, PanicLevel), ring), DebugLevel, &JsonPattern{})
+
+	for i := 0; i < 5; i++ {
+		log.Debug().Int("i", i).Msg("debug")
+	}
+	if out.Len() != 0 {
+		t.Error("DEBUG记录不应写入")
+	}
+
+	var records = ring.Records()
+	if len(records) != 3 || !strings.Contains(string(records[0]), `"i":2`) {
+		t.Errorf("应保留最近的3条记录:%q", records)
+	}
+
+	log.Fatal().Msg("fatal")
+	if strings.Count(dump.String(), "\n") != 3 || !strings.Contains(dump.String(), "fatal") {
+		t.Errorf("FATAL记录应触发写出:%s", dump.String())
+	}
+
+	var manual bytes.Buffer
+	log.DumpRing(&Stdout{Writer: &manual})
+	if manual.String() != dump.String() {
+		t.Error("DumpRing写出的内容不一致")
+	}
+}
+
+func TestRingWriterBytes(t *testing.T) {
+	var ring = NewRingWriter(0, 100)
+
+	for i := 0; i < 100; i++ {
+		_, _ = ring.Write([]byte("record-" + strconv.Itoa(i%10) + "\n"))
+	}
+
+	var records = ring.Records()
+	var size int
+	for _, r := range records {
+		size += len(r)
+	}
+	if size > 100 || len(records) != 11 {
+		t.Errorf("保留了%d条，%d字节", len(records), size)
+	}
+}
@@ -0,0 +1,264 @@
+package onelog
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+//RotateSchedule 按时间切分日志文件的计划
+type RotateSchedule interface {
+	//Next 返回t之后的下一个切分时间点
+	Next(t time.Time) time.Time
+	//Label 返回以t开始的时间段在归档文件名中的表示
+	Label(t time.Time) string
+}
+
+//HourlySchedule 每个整点切分一次
+type HourlySchedule struct {
+	Location *time.Location
+}
+
+func (h *HourlySchedule) Next(t time.Time) time.Time {
+	t = t.In(location(h.Location))
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+}
+
+func (h *HourlySchedule) Label(t time.Time) string {
+	return t.In(location(h.Location)).Format("20060102T15")
+}
+
+//DailySchedule 每天零点切分一次
+type DailySchedule struct {
+	Location *time.Location
+}
+
+func (d *DailySchedule) Next(t time.Time) time.Time {
+	t = t.In(location(d.Location))
+	return time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+}
+
+func (d *DailySchedule) Label(t time.Time) string {
+	return t.In(location(d.Location)).Format("20060102")
+}
+
+//CronSchedule 使用类似cron的表达式进行切分，格式为"分 时 日 月 周"，
+//每一项支持 *、数字、a-b 范围、a,b 列表与 /n 步长
+type CronSchedule struct {
+	minute   uint64
+	hour     uint64
+	dom      uint64
+	month    uint64
+	dow      uint64
+	anyDom   bool
+	anyDow   bool
+	location *time.Location
+}
+
+//NewCronSchedule 解析一个cron表达式，loc为nil时使用本地时间
+func NewCronSchedule(spec string, loc *time.Location) (*CronSchedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, &MistakeType{"分 时 日 月 周", spec}
+	}
+
+	var c = &CronSchedule{location: loc}
+	var err error
+
+	if c.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, err
+	}
+	if c.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, err
+	}
+	if c.dom, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, err
+	}
+	if c.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, err
+	}
+	if c.dow, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, err
+	}
+	//周日可以使用0或7表示
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+
+	c.anyDom = fields[2] == "*"
+	c.anyDow = fields[4] == "*"
+
+	//只指定日时，需要至少有一个月份包含其中的某一天，如"0 0 31 2 *"永远不会切分
+	if c.anyDow && !c.possible() {
+		return nil, NotUnderstand("cron:" + spec)
+	}
+
+	return c, nil
+}
+
+//possible 指定的月份中是否有指定的日，2月按29天计算
+func (c *CronSchedule) possible() bool {
+	var days = [13]int{0, 31, 29, 31, 30, 31, 30, 31, 31, 30, 31, 30, 31}
+
+	for m := 1; m <= 12; m++ {
+		if c.month&(1<<uint(m)) == 0 {
+			continue
+		}
+		for d := 1; d <= days[m]; d++ {
+			if c.dom&(1<<uint(d)) != 0 {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+//parseCronField 将cron表达式中的一项解析为位集合
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+
+	for _, part := range strings.Split(field, ",") {
+		var step = 1
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			s, err := strconv.Atoi(part[i+1:])
+			if err != nil || s <= 0 {
+				return 0, NotUnderstand("cron:" + field)
+			}
+			step = s
+			part = part[:i]
+		}
+
+		var from, to = min, max
+		if part != "*" {
+			if i := strings.IndexByte(part, '-'); i >= 0 {
+				var err error
+				if from, err = strconv.Atoi(part[:i]); err != nil {
+					return 0, NotUnderstand("cron:" + field)
+				}
+				if to, err = strconv.Atoi(part[i+1:]); err != nil {
+					return 0, NotUnderstand("cron:" + field)
+				}
+			} else {
+				v, err := strconv.Atoi(part)
+				if err != nil {
+					return 0, NotUnderstand("cron:" + field)
+				}
+				from = v
+				//只有给出步长时 a/n 才表示从a开始到最大值
+				if step == 1 {
+					to = v
+				}
+			}
+		}
+
+		if from < min || to > max || from > to {
+			return 0, &MistakeType{strconv.Itoa(min) + ".." + strconv.Itoa(max), field}
+		}
+
+		for v := from; v <= to; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+
+	return bits, nil
+}
+
+func (c *CronSchedule) dayMatch(t time.Time) bool {
+	var domOk = c.dom&(1<<uint(t.Day())) != 0
+	var dowOk = c.dow&(1<<uint(t.Weekday())) != 0
+
+	switch {
+	case c.anyDom && c.anyDow:
+		return true
+	case c.anyDom:
+		return dowOk
+	case c.anyDow:
+		return domOk
+	}
+
+	//日与周同时指定时，满足其一即可
+	return domOk || dowOk
+}
+
+func (c *CronSchedule) Next(t time.Time) time.Time {
+	loc := location(c.location)
+	t = t.In(loc)
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()+1, 0, 0, loc)
+
+	//最多向后查找5年，不可能满足的表达式在解析时已经拒绝，2月29日也能在此范围内找到
+	var limit = t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !c.dayMatch(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		return t
+	}
+
+	return limit
+}
+
+func (c *CronSchedule) Label(t time.Time) string {
+	return t.In(location(c.location)).Format("20060102T1504")
+}
+
+//lastBoundary 返回不晚于now的最近一个切分时间点，即now所在时间段的开始。5年内没有时返回now
+func lastBoundary(schedule RotateSchedule, now time.Time) time.Time {
+	//向前找到一个不晚于now的时间点，再向后逐个查找
+	for d := time.Minute; d <= 5*366*24*time.Hour; d *= 2 {
+		var t = schedule.Next(now.Add(-d))
+		if t.After(now) {
+			continue
+		}
+
+		for {
+			var next = schedule.Next(t)
+			if next.After(now) {
+				return t
+			}
+			t = next
+		}
+	}
+
+	return now
+}
+
+//NewRotateSchedule 根据配置值生成切分计划，支持"hourly"、"daily"与cron表达式
+func NewRotateSchedule(spec string, utc bool) (RotateSchedule, error) {
+	var loc = time.Local
+	if utc {
+		loc = time.UTC
+	}
+
+	switch strings.ToLower(strings.TrimSpace(spec)) {
+	case "hourly":
+		return &HourlySchedule{loc}, nil
+	case "daily":
+		return &DailySchedule{loc}, nil
+	}
+
+	return NewCronSchedule(spec, loc)
+}
+
+func location(loc *time.Location) *time.Location {
+	if loc == nil {
+		return time.Local
+	}
+
+	return loc
+}
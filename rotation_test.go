@@ -0,0 +1,130 @@
+package onelog
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCronScheduleNext(t *testing.T) {
+	var base = time.Date(2021, 3, 31, 22, 17, 30, 0, time.UTC)
+
+	var cases = []struct {
+		spec string
+		next time.Time
+	}{
+		{"* * * * *", time.Date(2021, 3, 31, 22, 18, 0, 0, time.UTC)},
+		{"0 * * * *", time.Date(2021, 3, 31, 23, 0, 0, 0, time.UTC)},
+		{"30 2 * * *", time.Date(2021, 4, 1, 2, 30, 0, 0, time.UTC)},
+		{"*/20 */6 * * *", time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 0", time.Date(2021, 4, 4, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2021, 4, 4, 0, 0, 0, 0, time.UTC)},
+		{"15 10 29 2 *", time.Date(2024, 2, 29, 10, 15, 0, 0, time.UTC)},
+	}
+
+	for _, c := range cases {
+		s, err := NewCronSchedule(c.spec, time.UTC)
+		if err != nil {
+			t.Errorf("%s: %v", c.spec, err)
+			continue
+		}
+		if next := s.Next(base); !next.Equal(c.next) {
+			t.Errorf("%s: 预期%v 实际%v", c.spec, c.next, next)
+		}
+	}
+
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "a * * * *", "5-1 * * * *", "*/0 * * * *", "0 0 31 2 *", "0 0 30,31 2 *", "0 0 31 4,6 *"} {
+		if _, err := NewCronSchedule(spec, time.UTC); err == nil {
+			t.Errorf("%q 应当返回错误", spec)
+		}
+	}
+	//同时指定周时满足其一即可
+	if _, err := NewCronSchedule("0 0 31 2 1", time.UTC); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLastBoundary(t *testing.T) {
+	var now = time.Date(2021, 4, 5, 9, 30, 0, 0, time.UTC)
+
+	daily, _ := NewRotateSchedule("daily", true)
+	if b := lastBoundary(daily, now); !b.Equal(time.Date(2021, 4, 5, 0, 0, 0, 0, time.UTC)) {
+		t.Error("daily", b)
+	}
+
+	cron, _ := NewCronSchedule("0 0 1 * *", time.UTC)
+	if b := lastBoundary(cron, now); !b.Equal(time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Error("cron", b)
+	}
+	//正好在切分时间点时即为此时间段的开始
+	if b := lastBoundary(cron, time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC)); !b.Equal(time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Error("cron boundary", b)
+	}
+}
+
+func TestBuiltinSchedule(t *testing.T) {
+	var base = time.Date(2021, 12, 31, 23, 59, 0, 0, time.UTC)
+
+	hourly, _ := NewRotateSchedule("hourly", true)
+	if next := hourly.Next(base); !next.Equal(time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Error("hourly", next)
+	}
+	if hourly.Label(base) != "20211231T23" {
+		t.Error("hourly label", hourly.Label(base))
+	}
+
+	daily, _ := NewRotateSchedule("Daily", true)
+	if next := daily.Next(base.Add(-12 * time.Hour)); !next.Equal(time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Error("daily", next)
+	}
+	if daily.Label(base) != "20211231" {
+		t.Error("daily label", daily.Label(base))
+	}
+}
+
+//everySchedule 每隔固定时间切分，仅用于测试
+type everySchedule time.Duration
+
+func (e everySchedule) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(e))
+}
+
+func (e everySchedule) Label(t time.Time) string {
+	return t.Format("150405.000")
+}
+
+func TestFileWriterRotateByTime(t *testing.T) {
+	dir := t.TempDir()
+	fw, err := NewFileWriter(filepath.Join(dir, "time.log"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.SetRotateSchedule(everySchedule(30 * time.Millisecond))
+
+	var log = New(fw, InfoLevel, &JsonPattern{})
+	log.Info().Msg("first period")
+	//缓存内容需要写入文件才能被切分
+	fw.mutex.Lock()
+	fw.writeToDisk(false)
+	fw.mutex.Unlock()
+
+	time.Sleep(40 * time.Millisecond)
+	log.Info().Msg("second period")
+	log.Close()
+
+	//切分后的压缩在后台进行
+	var archives []string
+	for i := 0; i < 100 && len(archives) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+		archives, _ = filepath.Glob(filepath.Join(dir, "time.log.*.gz"))
+	}
+	if len(archives) != 1 {
+		t.Fatalf("预期1个归档文件，实际%v", archives)
+	}
+
+	if b, _ := os.ReadFile(filepath.Join(dir, "time.log")); len(b) == 0 {
+		t.Error("当前文件应当包含第二个时间段的记录")
+	}
+}
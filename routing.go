@@ -0,0 +1,87 @@
+package onelog
+
+//LevelFilter 只写入指定等级范围内记录的Writer，用于将不同等级的记录路由至不同的Writer。
+//未知等级的记录(直接调用Write)将全部写入
+type LevelFilter struct {
+	Writer   Writer
+	MinLevel Level
+	MaxLevel Level
+}
+
+//NewLevelFilter 返回一个只写入min至max等级(包含)记录的Writer
+func NewLevelFilter(writer Writer, min, max Level) *LevelFilter {
+	return &LevelFilter{
+		Writer:   writer,
+		MinLevel: min,
+		MaxLevel: max,
+	}
+}
+
+func (f *LevelFilter) Write(p []byte) (n int, err error) {
+	return f.Writer.Write(p)
+}
+
+//WriteLevel 等级不在范围内的记录直接丢弃
+func (f *LevelFilter) WriteLevel(level Level, p []byte) (n int, err error) {
+	if level != Disable && (level < f.MinLevel || level > f.MaxLevel) {
+		return len(p), nil
+	}
+
+	return writeLevel(f.Writer, level, p)
+}
+
+func (f *LevelFilter) Close() {
+	f.Writer.Close()
+}
+
+func (f *LevelFilter) Flush() {
+	flush(f.Writer)
+}
+
+func (f *LevelFilter) flushSoon() {
+	flushSoon(f.Writer)
+}
+
+//SetConfig 设置相关参数，与MultipleWriter中的一项相同，增加MinLevel与MaxLevel两个值
+func (f *LevelFilter) SetConfig(config interface{}) error {
+	var conf, ok = config.(map[string]interface{})
+	if !ok {
+		return &MistakeType{"map[string]interface {} type", ""}
+	}
+
+	writer, err := newWriterFromConfig(conf)
+	if err != nil {
+		return err
+	}
+
+	f.Writer = writer
+	f.MinLevel, f.MaxLevel, err = levelRange(conf)
+
+	return err
+}
+
+//levelRange 从配置节中得到MinLevel与MaxLevel，未指定时为全部等级
+func levelRange(config map[string]interface{}) (min, max Level, err error) {
+	min, max = TraceLevel, PanicLevel
+
+	if val, ok := config["MinLevel"]; ok {
+		if min, err = parseLevel(val); err != nil {
+			return
+		}
+	}
+	if val, ok := config["MaxLevel"]; ok {
+		if max, err = parseLevel(val); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+//hasLevelRange 配置节中是否指定了等级范围
+func hasLevelRange(config map[string]interface{}) bool {
+	_, min := config["MinLevel"]
+	_, max := config["MaxLevel"]
+
+	return min || max
+}
@@ -0,0 +1,83 @@
+package onelog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestLevelRouting(t *testing.T) {
+	var console, errors bytes.Buffer
+
+	var log = New(NewLevelFilter(&Stdout{Writer: &console}, TraceLevel, DebugLevel), TraceLevel, &JsonPattern{})
+	log.Error().AddStatic("app", "test")
+	log.AddWriter(NewLevelFilter(&Stdout{Writer: &errors}, ErrorLevel, PanicLevel))
+
+	log.Debug().Msg("debug")
+	log.Info().Msg("info")
+	log.Error().Msg("error")
+
+	if strings.Count(console.String(), "\n") != 1 || !strings.Contains(console.String(), "debug") {
+		t.Errorf("console只应有DEBUG记录:%s", console.String())
+	}
+	if strings.Count(errors.String(), "\n") != 1 || !strings.Contains(errors.String(), `"app":"test"`) {
+		t.Errorf("errors只应有ERROR记录:%s", errors.String())
+	}
+}
+
+func TestAddWriterKeepsLevelWriter(t *testing.T) {
+	var all, audit, added bytes.Buffer
+
+	var log = New(&Stdout{Writer: &all}, InfoLevel, &JsonPattern{})
+	log.SetLevelWriter(WarnLevel, NewLevelWriter(&Stdout{Writer: &audit}, WarnLevel, &JsonPattern{}))
+	log.AddWriter(&Stdout{Writer: &added})
+
+	log.Info().Msg("info")
+	log.Warn().Msg("warn")
+
+	if !strings.Contains(audit.String(), "warn") || strings.Contains(all.String(), "warn") || strings.Contains(added.String(), "warn") {
+		t.Errorf("单独指定的WARN应只写入audit:%s|%s|%s", audit.String(), all.String(), added.String())
+	}
+	if !strings.Contains(all.String(), "info") || !strings.Contains(added.String(), "info") {
+		t.Errorf("其他等级应同时写入增加的Writer:%s|%s", all.String(), added.String())
+	}
+}
+
+func TestLevelRoutingConfig(t *testing.T) {
+	var m = &MultipleWriter{}
+	err := m.SetConfig([]interface{}{
+		map[string]interface{}{
+			"Writer":     "console",
+			"WriterPara": map[string]interface{}{"Console": "stdout"},
+			"MaxLevel":   "debug",
+		},
+		map[string]interface{}{
+			"Writer":     "console",
+			"WriterPara": map[string]interface{}{"Console": "stderr"},
+			"MinLevel":   float64(ErrorLevel),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for curr := m; curr != nil; curr = curr.Next {
+		f, ok := curr.Writer.(*LevelFilter)
+		if !ok {
+			t.Fatalf("%T 应为 *LevelFilter", curr.Writer)
+		}
+		if f.MinLevel != TraceLevel && f.MinLevel != ErrorLevel {
+			t.Error(f.MinLevel)
+		}
+	}
+
+	if err = m.SetConfig([]interface{}{
+		map[string]interface{}{
+			"Writer":     "console",
+			"WriterPara": map[string]interface{}{"Console": "stdout"},
+			"MinLevel":   "verbose",
+		},
+	}); err == nil {
+		t.Error("错误的等级应返回错误")
+	}
+}
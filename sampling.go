@@ -0,0 +1,309 @@
+package onelog
+
+import (
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//Sampler 采样器，决定一条记录是否写入。在生成记录之前调用，未被选中的记录几乎没有开销
+type Sampler interface {
+	//Sample 返回此条记录是否写入
+	Sample(level Level) bool
+}
+
+//BurstSampler 每个Period内写入前Burst条记录，之后每Every条写入1条，Every为0时丢弃之后所有的记录
+type BurstSampler struct {
+	Burst   uint32
+	Period  time.Duration
+	Every   uint32
+	counter uint32
+	resetAt int64
+}
+
+func (s *BurstSampler) Sample(level Level) bool {
+	var c = s.inc()
+	if c <= s.Burst {
+		return true
+	}
+
+	return s.Every > 0 && (c-s.Burst)%s.Every == 0
+}
+
+//inc 增加当前时间段内的计数，时间段结束后重新计数
+func (s *BurstSampler) inc() uint32 {
+	var now = time.Now().UnixNano()
+	var resetAt = atomic.LoadInt64(&s.resetAt)
+
+	if now > resetAt && atomic.CompareAndSwapInt64(&s.resetAt, resetAt, now+s.Period.Nanoseconds()) {
+		atomic.StoreUint32(&s.counter, 1)
+		return 1
+	}
+
+	return atomic.AddUint32(&s.counter, 1)
+}
+
+//EverySampler 每N条记录写入1条，第一条记录总是写入
+type EverySampler struct {
+	N       uint32
+	counter uint32
+}
+
+func (s *EverySampler) Sample(level Level) bool {
+	if s.N <= 1 {
+		return true
+	}
+
+	return (atomic.AddUint32(&s.counter, 1)-1)%s.N == 0
+}
+
+//RandomSampler 按Probability(0..1)的概率随机写入
+type RandomSampler struct {
+	Probability float64
+}
+
+func (s *RandomSampler) Sample(level Level) bool {
+	return s.Probability >= 1 || rand.Float64() < s.Probability
+}
+
+//sampling Logger的采样设置，以及每个等级被丢弃的记录条数
+type sampling struct {
+	//rules 当前的*samplingRules，设置时复制后整体替换，记录时无需加锁
+	rules   atomic.Value
+	mutex   sync.Mutex
+	dropped [Disable]uint64
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+//samplingRules 每个等级的采样器与限流
+type samplingRules struct {
+	samplers [Disable]Sampler
+	limiter  *RateLimiter
+}
+
+//load 返回当前的采样设置，未设置时为nil
+func (s *sampling) load() *samplingRules {
+	if s == nil {
+		return nil
+	}
+
+	r, _ := s.rules.Load().(*samplingRules)
+	return r
+}
+
+//update 复制当前的采样设置，修改后替换
+func (s *sampling) update(change func(r *samplingRules)) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var r = &samplingRules{}
+	if old := s.load(); old != nil {
+		*r = *old
+	}
+	change(r)
+	s.rules.Store(r)
+}
+
+//SetSampler 为指定的等级设置采样器，未指定等级时用于所有等级。sampler为nil时取消采样
+func (l *Logger) SetSampler(sampler Sampler, levels ...Level) *Logger {
+	if l.base != nil {
+		l.base.SetSampler(sampler, levels...)
+		return l
+	}
+
+	if len(levels) == 0 {
+		for i := TraceLevel; i <= PanicLevel; i++ {
+			levels = append(levels, i)
+		}
+	}
+
+	l.sampling.update(func(r *samplingRules) {
+		for _, level := range levels {
+			if level <= PanicLevel {
+				r.samplers[level] = sampler
+			}
+		}
+	})
+
+	return l
+}
+
+//SetSampleSummary 每隔interval为有记录被丢弃的等级写入一条汇总记录，DroppedName项为此期间丢弃的条数。
+//interval小于等于0时停止汇总
+func (l *Logger) SetSampleSummary(interval time.Duration) *Logger {
+	if l.base != nil {
+		l.base.SetSampleSummary(interval)
+		return l
+	}
+
+	l.stopSampleSummary()
+
+	if interval > 0 {
+		var s = l.sampling
+		s.stop = make(chan struct{})
+		s.done = make(chan struct{})
+
+		go func(stop, done chan struct{}) {
+			defer close(done)
+
+			var ticker = time.NewTicker(interval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ticker.C:
+					l.writeSampleSummary()
+				case <-stop:
+					return
+				}
+			}
+		}(s.stop, s.done)
+	}
+
+	return l
+}
+
+func (l *Logger) stopSampleSummary() {
+	if l.sampling != nil && l.sampling.stop != nil {
+		close(l.sampling.stop)
+		<-l.sampling.done
+		l.sampling.stop = nil
+	}
+}
+
+//writeSampleSummary 写入每个等级被丢弃的记录条数，并重新计数
+func (l *Logger) writeSampleSummary() {
+	for i := TraceLevel; i <= PanicLevel; i++ {
+		if n := atomic.SwapUint64(&l.sampling.dropped[i], 0); n > 0 {
+			if lw := l.summaryWriter(i); lw != nil {
+				lw.Uint64(DroppedName, n).Msg("sampled out")
+			}
+		}
+	}
+}
+
+//sampledOut 按采样器与限流判断此等级的记录是否被丢弃
+func (l *Logger) sampledOut(level Level) bool {
+	var r = l.sampling.load()
+	if r == nil {
+		return false
+	}
+
+	var s = r.samplers[level]
+	if (s == nil || s.Sample(level)) && (r.limiter == nil || r.limiter.Allow(level)) {
+		return false
+	}
+
+	atomic.AddUint64(&l.sampling.dropped[level], 1)
+	l.stats.addDropped(level)
+	return true
+}
+
+//Sampled 返回此等级自上次汇总以来被采样或限流丢弃的记录条数
+func (l *Logger) Sampled(level Level) uint64 {
+	if l.base != nil {
+		return l.base.Sampled(level)
+	}
+
+	if l.sampling == nil || level > PanicLevel {
+		return 0
+	}
+
+	return atomic.LoadUint64(&l.sampling.dropped[level])
+}
+
+//newSamplerFromConfig 根据配置生成采样器，以及其使用的等级
+func newSamplerFromConfig(config map[string]interface{}) (Sampler, []Level, error) {
+	var nums = map[string]float64{"Burst": 0, "Period": 1000, "Every": 0, "N": 0, "Probability": 0}
+	for key := range nums {
+		if val, ok := config[key]; ok {
+			switch val.(type) {
+			case float64:
+				if val.(float64) < 0 {
+					return nil, nil, &MistakeType{"大于等于0", strconv.FormatFloat(val.(float64), 'f', -1, 64)}
+				}
+				nums[key] = val.(float64)
+			default:
+				return nil, nil, &MistakeType{"number type", key}
+			}
+		}
+	}
+
+	var levels []Level
+	if val, ok := config["Levels"]; ok {
+		list, ok := val.([]interface{})
+		if !ok {
+			return nil, nil, &MistakeType{"[]string type", "Levels"}
+		}
+		for _, v := range list {
+			level, err := parseLevel(v)
+			if err != nil {
+				return nil, nil, err
+			}
+			levels = append(levels, level)
+		}
+	}
+
+	var kind, _ = config["Type"].(string)
+	var sampler Sampler
+	switch strings.ToLower(kind) {
+	case "burst":
+		//Period以毫秒为单位
+		sampler = &BurstSampler{
+			Burst:  uint32(nums["Burst"]),
+			Period: time.Duration(nums["Period"]) * time.Millisecond,
+			Every:  uint32(nums["Every"]),
+		}
+	case "every":
+		sampler = &EverySampler{N: uint32(nums["N"])}
+	case "random":
+		sampler = &RandomSampler{Probability: nums["Probability"]}
+	default:
+		return nil, nil, NotUnderstand("Sampling:Type:" + kind)
+	}
+
+	return sampler, levels, nil
+}
+
+//loadSampling 按配置中的Sampling与SampleSummary设置Logger的采样，Sampling可为一个或多个采样器的配置
+func loadSampling(log *Logger, config map[string]interface{}) error {
+	if val, ok := config["Sampling"]; ok {
+		var list []interface{}
+		switch val.(type) {
+		case map[string]interface{}:
+			list = []interface{}{val}
+		case []interface{}:
+			list = val.([]interface{})
+		default:
+			return NotUnderstand("Sampling")
+		}
+
+		for _, v := range list {
+			rec, ok := v.(map[string]interface{})
+			if !ok {
+				return NotUnderstand("数组内值必须为json")
+			}
+			sampler, levels, err := newSamplerFromConfig(rec)
+			if err != nil {
+				return err
+			}
+			log.SetSampler(sampler, levels...)
+		}
+	}
+
+	if val, ok := config["SampleSummary"]; ok {
+		switch val.(type) {
+		case float64:
+			//以毫秒为单位
+			log.SetSampleSummary(time.Duration(val.(float64)) * time.Millisecond)
+		default:
+			return &MistakeType{"number type", "SampleSummary"}
+		}
+	}
+
+	return nil
+}
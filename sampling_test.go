@@ -0,0 +1,135 @@
+package onelog
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSamplers(t *testing.T) {
+	var count = func(s Sampler, n int) int {
+		var kept = 0
+		for i := 0; i < n; i++ {
+			if s.Sample(InfoLevel) {
+				kept++
+			}
+		}
+		return kept
+	}
+
+	if kept := count(&BurstSampler{Burst: 10, Period: time.Hour, Every: 10}, 110); kept != 20 {
+		t.Errorf("burst:%d", kept)
+	}
+	if kept := count(&BurstSampler{Burst: 5, Period: time.Hour}, 100); kept != 5 {
+		t.Errorf("burst only:%d", kept)
+	}
+	if kept := count(&EverySampler{N: 4}, 100); kept != 25 {
+		t.Errorf("every:%d", kept)
+	}
+	if kept := count(&RandomSampler{Probability: 0}, 100); kept != 0 {
+		t.Errorf("random:%d", kept)
+	}
+}
+
+func TestLoggerSampling(t *testing.T) {
+	var buf bytes.Buffer
+	var log = New(&Stdout{&buf}, DebugLevel, &JsonPattern{})
+	log.SetSampler(&EverySampler{N: 10}, DebugLevel)
+
+	for i := 0; i < 100; i++ {
+		log.Debug().Msg("debug")
+		log.Info().Msg("info")
+	}
+
+	if n := strings.Count(buf.String(), "debug"); n != 10 {
+		t.Errorf("DEBUG应保留10条，实际%d条", n)
+	}
+	if n := strings.Count(buf.String(), `"msg":"info"`); n != 100 {
+		t.Errorf("INFO不应被采样，实际%d条", n)
+	}
+	if log.Sampled(DebugLevel) != 90 {
+		t.Errorf("丢弃条数不正确:%d", log.Sampled(DebugLevel))
+	}
+
+	buf.Reset()
+	log.SetSampleSummary(time.Hour)
+	log.Close()
+	if !strings.Contains(buf.String(), `"dropped":90`) {
+		t.Errorf("关闭时应写入汇总记录:%s", buf.String())
+	}
+}
+
+//TestSetSamplingConcurrent 记录日志的同时修改采样、限流与去重，使用-race运行
+func TestSetSamplingConcurrent(t *testing.T) {
+	var log = New(&Stdout{io.Discard}, TraceLevel, &JsonPattern{})
+	defer log.Close()
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				log.Info().Int("i", i).Msg("concurrent")
+			}
+		}()
+	}
+
+	for i := 0; i < 20; i++ {
+		log.SetSampler(&EverySampler{N: uint32(i%3 + 1)})
+		log.SetRateLimit(&RateLimiter{Rate: 1000, Burst: 100})
+		log.SetDedupe(time.Duration(i%2) * time.Second)
+	}
+	wg.Wait()
+
+	log.SetSampler(nil)
+	log.SetRateLimit(nil)
+	if log.sampledOut(InfoLevel) {
+		t.Error("取消采样与限流后不应丢弃")
+	}
+}
+
+//TestSampleSummaryConcurrent 后台写入汇总记录的同时修改等级与LevelWriter，使用-race运行
+func TestSampleSummaryConcurrent(t *testing.T) {
+	var log = New(&Stdout{io.Discard}, TraceLevel, &JsonPattern{})
+	defer log.Close()
+	log.SetSampler(&EverySampler{N: 2})
+	log.SetSampleSummary(time.Millisecond)
+
+	for i := 0; i < 200; i++ {
+		log.Info().Msg("sampled")
+		log.Info().Msg("sampled")
+		log.SetLevel(Level(i%2) + DebugLevel)
+		log.SetLevelWriter(InfoLevel, log.lws[InfoLevel].clone())
+		log.AddStatic("i", strconv.Itoa(i))
+		log.AddWriter(&Stdout{io.Discard})
+	}
+}
+
+func TestSamplingConfig(t *testing.T) {
+	var log = New(&Stdout{&bytes.Buffer{}}, TraceLevel, &JsonPattern{})
+	err := loadSampling(log, map[string]interface{}{
+		"Sampling": []interface{}{
+			map[string]interface{}{"Type": "burst", "Burst": float64(100), "Period": float64(1000), "Every": float64(10), "Levels": []interface{}{"Debug", "Info"}},
+			map[string]interface{}{"Type": "random", "Probability": 0.5, "Levels": []interface{}{"Trace"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s, ok := log.sampling.load().samplers[InfoLevel].(*BurstSampler); !ok || s.Burst != 100 || s.Period != time.Second {
+		t.Errorf("%#v", log.sampling.load().samplers[InfoLevel])
+	}
+	if _, ok := log.sampling.load().samplers[TraceLevel].(*RandomSampler); !ok || log.sampling.load().samplers[WarnLevel] != nil {
+		t.Error("等级设置不正确")
+	}
+
+	if err = loadSampling(log, map[string]interface{}{"Sampling": map[string]interface{}{"Type": "x"}}); err == nil {
+		t.Error("未知的Type应返回错误")
+	}
+}
@@ -0,0 +1,14 @@
+package onelog
+
+import (
+	"os"
+	"syscall"
+)
+
+//refSignal 配置文件中可使用的信号名称
+var refSignal = map[string]os.Signal{
+	"SIGHUP":  syscall.SIGHUP,
+	"SIGINT":  syscall.SIGINT,
+	"SIGQUIT": syscall.SIGQUIT,
+	"SIGTERM": syscall.SIGTERM,
+}
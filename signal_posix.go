@@ -0,0 +1,11 @@
+//go:build !windows
+// +build !windows
+
+package onelog
+
+import "syscall"
+
+func init() {
+	refSignal["SIGUSR1"] = syscall.SIGUSR1
+	refSignal["SIGUSR2"] = syscall.SIGUSR2
+}
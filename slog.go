@@ -0,0 +1,201 @@
+//go:build go1.21
+// +build go1.21
+
+package onelog
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"time"
+)
+
+//SlogHandler 使用Logger写入的slog.Handler，记录带有Logger的通用项并使用其Pattern与Writer。
+//slog的等级低于Debug时为TRACE，分组中的项名称使用"分组.名称"
+type SlogHandler struct {
+	logger *Logger
+	attrs  []slog.Attr
+	group  string
+}
+
+//NewSlogHandler 返回一个写入logger的slog.Handler
+func NewSlogHandler(logger *Logger) *SlogHandler {
+	return &SlogHandler{logger: logger}
+}
+
+//Slog 返回一个写入此Logger的*slog.Logger
+func (l *Logger) Slog() *slog.Logger {
+	return slog.New(NewSlogHandler(l))
+}
+
+//SlogLevel 将slog的等级转换为Level
+func SlogLevel(level slog.Level) Level {
+	switch {
+	case level < slog.LevelDebug:
+		return TraceLevel
+	case level < slog.LevelInfo:
+		return DebugLevel
+	case level < slog.LevelWarn:
+		return InfoLevel
+	case level < slog.LevelError:
+		return WarnLevel
+	}
+
+	return ErrorLevel
+}
+
+func (h *SlogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.logger.Enabled(SlogLevel(level))
+}
+
+//Handle 记录的时间与调用者使用slog.Record中的Time与PC，而不是写入时计算的值
+func (h *SlogHandler) Handle(_ context.Context, r slog.Record) error {
+	var lw = h.logger.WithLevel(SlogLevel(r.Level))
+	if _, ok := lw.(*DisableLevelWriter); ok {
+		return nil
+	}
+	if dlw, ok := lw.(*DefaultLevelWriter); ok {
+		dlw.runtimeComputes = dlw.runtimeComputes.withRecord(r.Time, r.PC)
+	}
+
+	for _, a := range h.attrs {
+		lw = appendAttr(lw, "", a)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		lw = appendAttr(lw, h.group, a)
+		return true
+	})
+	lw.Msg(r.Message)
+
+	return nil
+}
+
+//withRecord 返回一个新的链表，其中的TimeValue与Caller使用slog.Record中的时间与调用位置
+func (rs *RunTimeComputes) withRecord(t time.Time, pc uintptr) *RunTimeComputes {
+	if rs == nil {
+		return nil
+	}
+
+	var curr = rs.curr
+	switch curr.(type) {
+	case *TimeValue:
+		if !t.IsZero() {
+			curr = &recordTime{t}
+		}
+	case *Caller:
+		if pc != 0 {
+			curr = &recordCaller{pc}
+		}
+	}
+
+	return &RunTimeComputes{curr, rs.next.withRecord(t, pc)}
+}
+
+//recordTime slog.Record中的时间
+type recordTime struct {
+	t time.Time
+}
+
+func (*recordTime) GetName() string {
+	return TimeName
+}
+
+func (r *recordTime) Values() []byte {
+	return timeValue(r.t)
+}
+
+//recordCaller 由slog.Record中的PC得到的调用者
+type recordCaller struct {
+	pc uintptr
+}
+
+func (*recordCaller) GetName() string {
+	return CallerName
+}
+
+func (r *recordCaller) Values() []byte {
+	var frame, _ = runtime.CallersFrames([]uintptr{r.pc}).Next()
+
+	return callerValue(frame.File, frame.Line, frame.File != "")
+}
+
+//WithAttrs 返回带有这些项的Handler，项的名称使用当前的分组
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+
+	var result = *h
+	result.attrs = make([]slog.Attr, len(h.attrs), len(h.attrs)+len(attrs))
+	copy(result.attrs, h.attrs)
+	for _, a := range attrs {
+		if h.group != "" {
+			a.Key = h.group + a.Key
+		}
+		result.attrs = append(result.attrs, a)
+	}
+
+	return &result
+}
+
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	var result = *h
+	result.group = h.group + name + "."
+
+	return &result
+}
+
+//appendAttr 将一个slog.Attr转换为LevelWriter中的项，prefix为分组的前缀
+func appendAttr(lw LevelWriter, prefix string, a slog.Attr) LevelWriter {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return lw
+	}
+
+	var key = prefix + a.Key
+	switch a.Value.Kind() {
+	case slog.KindGroup:
+		var group = a.Value.Group()
+		if len(group) == 0 {
+			return lw
+		}
+		//名称为空的分组中的项直接使用当前的分组
+		if a.Key != "" {
+			prefix = key + "."
+		}
+		for _, ga := range group {
+			lw = appendAttr(lw, prefix, ga)
+		}
+		return lw
+	case slog.KindString:
+		return lw.String(key, a.Value.String())
+	case slog.KindInt64:
+		return lw.Int64(key, a.Value.Int64())
+	case slog.KindUint64:
+		return lw.Uint64(key, a.Value.Uint64())
+	case slog.KindFloat64:
+		return lw.Float64(key, a.Value.Float64())
+	case slog.KindBool:
+		return lw.Bool(key, a.Value.Bool())
+	case slog.KindDuration:
+		return lw.String(key, a.Value.Duration().String())
+	case slog.KindTime:
+		return lw.String(key, a.Value.Time().Format(TimeFormat))
+	}
+
+	switch v := a.Value.Any().(type) {
+	case error:
+		return lw.String(key, v.Error())
+	case []byte:
+		return lw.Bytes(key, v)
+	case fmt.Stringer:
+		return lw.String(key, v.String())
+	}
+
+	return lw.String(key, fmt.Sprint(a.Value.Any()))
+}
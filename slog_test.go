@@ -0,0 +1,84 @@
+//go:build go1.21
+// +build go1.21
+
+package onelog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type secret string
+
+func (secret) LogValue() slog.Value {
+	return slog.StringValue("***")
+}
+
+func TestSlogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	var logger = New(&Stdout{&buf}, InfoLevel, &JsonPattern{})
+	logger.AddStatic("app", "test")
+
+	var log = logger.Slog().With("req", 7).WithGroup("http")
+	log.Debug("hidden")
+	log.Warn("request",
+		slog.String("method", "GET"),
+		slog.Int("status", 500),
+		slog.Duration("took", time.Second),
+		slog.Any("password", secret("123")),
+		slog.Any("cause", errors.New("timeout")),
+		slog.Group("user", slog.String("name", "a"), slog.Bool("admin", false)),
+	)
+
+	var line = buf.String()
+	if strings.Contains(line, "hidden") || strings.Count(line, "\n") != 1 {
+		t.Fatalf("低于Logger等级的记录不应写入:%s", line)
+	}
+	for _, want := range []string{`"WARN"`, `"app":"test"`, `"req":7`, `"http.method":"GET"`, `"http.status":500`,
+		`"http.took":"1s"`, `"http.password":"***"`, `"http.cause":"timeout"`, `"http.user.name":"a"`, `"http.user.admin":false`, `"msg":"request"`} {
+		if !strings.Contains(line, want) {
+			t.Errorf("缺少%s:%s", want, line)
+		}
+	}
+}
+
+func TestSlogRecordCallerAndTime(t *testing.T) {
+	var buf bytes.Buffer
+	var logger = New(&Stdout{&buf}, InfoLevel, &JsonPattern{})
+	logger.AddRuntime(&Caller{})
+
+	logger.Slog().Info("here")
+	_, file, line, _ := runtime.Caller(0)
+	if want := file + " " + strconv.Itoa(line-1); !strings.Contains(buf.String(), want) {
+		t.Errorf("调用者应为slog的调用位置%s:%s", want, buf.String())
+	}
+
+	buf.Reset()
+	var at = time.Date(2001, 2, 3, 4, 5, 6, 0, time.Local)
+	_ = NewSlogHandler(logger).Handle(context.Background(), slog.NewRecord(at, slog.LevelInfo, "old", 0))
+	if want := string(timeValue(at)); !strings.Contains(buf.String(), want) {
+		t.Errorf("时间应为Record中的%s:%s", want, buf.String())
+	}
+}
+
+func TestSlogLevel(t *testing.T) {
+	var cases = map[slog.Level]Level{
+		slog.LevelDebug - 4: TraceLevel,
+		slog.LevelDebug:     DebugLevel,
+		slog.LevelInfo + 1:  InfoLevel,
+		slog.LevelWarn:      WarnLevel,
+		slog.LevelError + 4: ErrorLevel,
+	}
+	for s, l := range cases {
+		if SlogLevel(s) != l {
+			t.Errorf("%v: %v", s, SlogLevel(s))
+		}
+	}
+}
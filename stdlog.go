@@ -0,0 +1,169 @@
+package onelog
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"sync"
+)
+
+//maxLineLength 未遇到换行时缓存的最大长度，超过时作为一行写入
+const maxLineLength = 64 * 1024
+
+//LineWriter 将写入的内容按行切分，每一行作为一条指定等级的记录写入Logger。可用于exec.Cmd.Stderr等需要io.Writer的地方。
+//写入的位置不是实际的调用者，记录中不包含Caller计算的调用者
+type LineWriter struct {
+	//Prefix 需要去掉的前缀，与标准库log.SetPrefix设置的值相同
+	Prefix string
+	//StdLog 为true时去掉标准库log加入的日期、时间与文件名，文件名写入CallerName项。RedirectStdLog使用时为true
+	StdLog bool
+	logger *Logger
+	level  Level
+	buffer []byte
+	mutex  sync.Mutex
+}
+
+//NewLineWriter 返回一个以level等级写入logger的LineWriter
+func NewLineWriter(logger *Logger, level Level) *LineWriter {
+	return &LineWriter{
+		logger: logger,
+		level:  level,
+	}
+}
+
+//Writer 返回一个以level等级写入此Logger的io.Writer
+func (l *Logger) Writer(level Level) io.WriteCloser {
+	return NewLineWriter(l, level)
+}
+
+func (w *LineWriter) Write(p []byte) (n int, err error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.buffer = append(w.buffer, p...)
+	for {
+		var i = bytes.IndexByte(w.buffer, '\n')
+		if i < 0 {
+			break
+		}
+		w.writeLine(w.buffer[:i])
+		w.buffer = w.buffer[i+1:]
+	}
+
+	if len(w.buffer) >= maxLineLength {
+		w.writeLine(w.buffer)
+		w.buffer = w.buffer[:0]
+	}
+	//不再持有已写入部分的空间
+	if len(w.buffer) == 0 {
+		w.buffer = nil
+	}
+
+	return len(p), nil
+}
+
+//Close 将没有换行的最后一行写入
+func (w *LineWriter) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if len(w.buffer) > 0 {
+		w.writeLine(w.buffer)
+		w.buffer = nil
+	}
+
+	return nil
+}
+
+func (w *LineWriter) writeLine(line []byte) {
+	line = bytes.TrimRight(line, "\r")
+	line = bytes.TrimPrefix(line, []byte(w.Prefix))
+
+	var caller string
+	if w.StdLog {
+		line, caller = trimStdPrefix(line)
+	}
+	if len(line) == 0 {
+		return
+	}
+
+	//运行时计算的调用者为此处，不是实际写入的位置。标准库log给出了文件名时使用此文件名
+	var lw = w.logger.WithLevel(w.level)
+	if dlw, ok := lw.(*DefaultLevelWriter); ok {
+		dlw.runtimeComputes = dlw.runtimeComputes.withoutCaller()
+	}
+	if caller != "" {
+		lw = lw.String(CallerName, caller)
+	}
+	lw.Msg(string(line))
+}
+
+//trimStdPrefix 去掉标准库log加入的日期、时间与文件名，返回剩余的内容与文件名
+func trimStdPrefix(line []byte) ([]byte, string) {
+	//2009/01/23
+	if matchDigits(line, "dddd/dd/dd ") {
+		line = line[11:]
+	}
+	//01:23:23 或 01:23:23.123123
+	if matchDigits(line, "dd:dd:dd") {
+		line = line[8:]
+		if len(line) > 0 && line[0] == '.' {
+			var i = 1
+			for i < len(line) && line[i] >= '0' && line[i] <= '9' {
+				i++
+			}
+			line = line[i:]
+		}
+		line = bytes.TrimPrefix(line, []byte(" "))
+	}
+
+	//file.go:23:
+	var caller string
+	if i := bytes.Index(line, []byte(": ")); i > 0 {
+		var name = line[:i]
+		if j := bytes.LastIndexByte(name, ':'); j > 0 && bytes.Contains(name[:j], []byte(".go")) && matchDigits(name[j+1:], "d") {
+			caller = string(name)
+			line = line[i+2:]
+		}
+	}
+
+	return line, caller
+}
+
+//matchDigits 判断line是否以layout的格式开始，layout中的d表示一个数字，其他字符需要相同
+func matchDigits(line []byte, layout string) bool {
+	if len(line) < len(layout) {
+		return false
+	}
+
+	for i := 0; i < len(layout); i++ {
+		if layout[i] == 'd' {
+			if line[i] < '0' || line[i] > '9' {
+				return false
+			}
+		} else if line[i] != layout[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+//RedirectStdLog 将标准库log的输出以level等级写入logger，返回恢复原设置的方法。
+//会保留log的Lshortfile与Llongfile设置，文件名写入CallerName项
+func RedirectStdLog(logger *Logger, level Level) func() {
+	var flags, prefix, output = log.Flags(), log.Prefix(), log.Writer()
+	var w = NewLineWriter(logger, level)
+	w.StdLog = true
+
+	log.SetFlags(flags & (log.Lshortfile | log.Llongfile))
+	log.SetPrefix("")
+	log.SetOutput(w)
+
+	return func() {
+		log.SetOutput(output)
+		log.SetPrefix(prefix)
+		log.SetFlags(flags)
+		w.Close()
+	}
+}
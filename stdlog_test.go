@@ -0,0 +1,105 @@
+package onelog
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLineWriter(t *testing.T) {
+	var buf bytes.Buffer
+	var logger = New(&Stdout{&buf}, DebugLevel, &JsonPattern{})
+
+	var w = logger.Writer(WarnLevel)
+	fmt.Fprint(w, "first line\r\nsecond ")
+	fmt.Fprint(w, "line\n\nlast")
+	if n := strings.Count(buf.String(), "WARN"); n != 2 {
+		t.Fatalf("应写入两行:%s", buf.String())
+	}
+	w.Close()
+
+	var lines = strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[0], `"msg":"first line"`) || !strings.Contains(lines[1], `"msg":"second line"`) || !strings.Contains(lines[2], `"msg":"last"`) {
+		t.Errorf("%s", buf.String())
+	}
+}
+
+func TestTrimStdPrefix(t *testing.T) {
+	var cases = []struct{ line, msg, caller string }{
+		{"2009/01/23 01:23:23 hello", "hello", ""},
+		{"2009/01/23 01:23:23.123123 main.go:23: hello: world", "hello: world", "main.go:23"},
+		{"/a/b/c.go:7: x", "x", "/a/b/c.go:7"},
+		{"key: value", "key: value", ""},
+	}
+
+	for _, c := range cases {
+		if msg, caller := trimStdPrefix([]byte(c.line)); string(msg) != c.msg || caller != c.caller {
+			t.Errorf("%q: %q %q", c.line, msg, caller)
+		}
+	}
+}
+
+func TestRedirectStdLog(t *testing.T) {
+	var buf bytes.Buffer
+	var logger = New(&Stdout{&buf}, DebugLevel, &JsonPattern{})
+	logger.AddRuntime(&Caller{})
+
+	log.SetPrefix("[lib] ")
+	var restore = RedirectStdLog(logger, InfoLevel)
+	log.Printf("connected to %s", "db")
+	restore()
+	log.SetPrefix("")
+
+	if !strings.Contains(buf.String(), `"INFO"`) || !strings.Contains(buf.String(), `"msg":"connected to db"`) {
+		t.Errorf("%s", buf.String())
+	}
+	//未设置Lshortfile时没有调用者，不应记录LineWriter的位置
+	if strings.Contains(buf.String(), `"caller"`) {
+		t.Errorf("%s", buf.String())
+	}
+	if log.Writer() != os.Stderr || log.Flags() != log.LstdFlags {
+		t.Error("应恢复原设置")
+	}
+}
+
+func TestLineWriterCaller(t *testing.T) {
+	var buf bytes.Buffer
+	var logger = New(&Stdout{&buf}, DebugLevel, &JsonPattern{})
+	logger.AddRuntime(&Caller{})
+
+	var w = NewLineWriter(logger, InfoLevel)
+	w.StdLog = true
+	fmt.Fprint(w, "main.go:23: with file\nwithout file\n")
+	logger.Info().Msg("direct")
+
+	var lines = strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatal(buf.String())
+	}
+	if strings.Count(lines[0], `"caller"`) != 1 || !strings.Contains(lines[0], `"caller":"main.go:23"`) {
+		t.Errorf("应只有标准库log给出的调用者:%s", lines[0])
+	}
+	if strings.Contains(lines[1], `"caller"`) {
+		t.Errorf("没有文件名时不应有调用者:%s", lines[1])
+	}
+	if strings.Count(lines[2], `"caller"`) != 1 {
+		t.Errorf("直接记录的日志应保留运行时计算的调用者:%s", lines[2])
+	}
+}
+
+func TestLineWriterKeepsText(t *testing.T) {
+	var buf bytes.Buffer
+	var logger = New(&Stdout{&buf}, DebugLevel, &JsonPattern{})
+	logger.AddRuntime(&Caller{})
+
+	//不是标准库log的输出时不去掉文件名
+	var w = logger.Writer(WarnLevel)
+	fmt.Fprint(w, "pkg/x.go:12: compile error\n")
+
+	if !strings.Contains(buf.String(), `"msg":"pkg/x.go:12: compile error"`) || strings.Contains(buf.String(), `"caller"`) {
+		t.Error(buf.String())
+	}
+}
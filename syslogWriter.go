@@ -0,0 +1,441 @@
+package onelog
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//SyslogFormat syslog消息的格式
+type SyslogFormat uint8
+
+const (
+	//RFC5424 新版的syslog格式
+	RFC5424 SyslogFormat = iota
+	//RFC3164 旧版BSD的syslog格式
+	RFC3164
+)
+
+//Facility syslog的设施值
+type Facility int
+
+var refFacility = map[string]Facility{
+	"kern":     0,
+	"user":     1,
+	"mail":     2,
+	"daemon":   3,
+	"auth":     4,
+	"syslog":   5,
+	"lpr":      6,
+	"news":     7,
+	"uucp":     8,
+	"cron":     9,
+	"authpriv": 10,
+	"ftp":      11,
+	"local0":   16,
+	"local1":   17,
+	"local2":   18,
+	"local3":   19,
+	"local4":   20,
+	"local5":   21,
+	"local6":   22,
+	"local7":   23,
+}
+
+//severity 将日志等级转换为syslog的严重程度
+func severity(level Level) int {
+	switch level {
+	case TraceLevel, DebugLevel:
+		return 7
+	case InfoLevel:
+		return 6
+	case WarnLevel:
+		return 4
+	case ErrorLevel:
+		return 3
+	case FatalLevel:
+		return 2
+	case PanicLevel:
+		return 0
+	}
+	//未知等级的记录使用notice
+	return 5
+}
+
+//syslogTimeout 连接与每次发送的超时时间
+const syslogTimeout = 5 * time.Second
+
+//NotConnected 连接失败后等待重连期间写入时返回的错误
+type NotConnected string
+
+func (e NotConnected) Error() string {
+	return string(e) + "未连接，等待重连"
+}
+
+//SyslogWriter 将日志发送至syslog服务的Writer，支持unix socket、UDP、TCP与TLS，连接断开时将自动重连
+type SyslogWriter struct {
+	network   string
+	address   string
+	facility  Facility
+	appName   string
+	hostname  string
+	format    SyslogFormat
+	tlsConfig *tls.Config
+	conn      net.Conn
+	stream    bool
+	retryAt   time.Time
+	buffer    []byte
+	frame     []byte
+	failed    uint64
+	mutex     sync.Mutex
+	writerCounters
+}
+
+//NewSyslogWriter 返回一个新的SyslogWriter。network可为unix、udp、tcp、tls，
+//network为unix且address为空时使用本机的/dev/log。连接失败时不返回错误，写入时将自动重连
+func NewSyslogWriter(network, address string, facility Facility, appName string) (*SyslogWriter, error) {
+	if err := checkNetwork(network); err != nil {
+		return nil, err
+	}
+
+	var hostname, _ = os.Hostname()
+	if appName == "" {
+		appName = os.Args[0][strings.LastIndexByte(os.Args[0], '/')+1:]
+	}
+	if network == "unix" && address == "" {
+		address = "/dev/log"
+	}
+
+	var s = &SyslogWriter{
+		network:  network,
+		address:  address,
+		facility: facility,
+		appName:  appName,
+		hostname: hostname,
+		format:   RFC5424,
+		buffer:   make([]byte, 0, 1024),
+	}
+
+	_ = s.connect()
+
+	return s, nil
+}
+
+func checkNetwork(network string) error {
+	switch network {
+	case "unix", "udp", "tcp", "tls":
+		return nil
+	}
+
+	return NotUnderstand("Network:" + network)
+}
+
+//SetFormat 设置消息的格式
+func (s *SyslogWriter) SetFormat(format SyslogFormat) *SyslogWriter {
+	s.mutex.Lock()
+	s.format = format
+	s.mutex.Unlock()
+
+	return s
+}
+
+//SetHostname 设置消息中的主机名称，默认为os.Hostname()
+func (s *SyslogWriter) SetHostname(hostname string) *SyslogWriter {
+	s.mutex.Lock()
+	s.hostname = hostname
+	s.mutex.Unlock()
+
+	return s
+}
+
+//SetTLSConfig 设置network为tls时使用的配置，设置后将重新连接
+func (s *SyslogWriter) SetTLSConfig(config *tls.Config) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.tlsConfig = config
+	s.closeConn()
+
+	return s.connect()
+}
+
+//connect 建立连接，调用方需要持有锁
+func (s *SyslogWriter) connect() error {
+	var conn net.Conn
+	var err error
+
+	s.stream = s.network != "udp"
+
+	switch s.network {
+	case "unix":
+		//本机的syslog一般为unixgram，不支持时再使用unix
+		if conn, err = net.Dial("unixgram", s.address); err == nil {
+			s.stream = false
+		} else {
+			conn, err = net.Dial("unix", s.address)
+		}
+	case "tls":
+		conn, err = tls.DialWithDialer(&net.Dialer{Timeout: syslogTimeout}, "tcp", s.address, s.tlsConfig)
+	case "udp", "tcp":
+		conn, err = net.DialTimeout(s.network, s.address, syslogTimeout)
+	default:
+		return NotUnderstand("Network:" + s.network)
+	}
+
+	if err != nil {
+		//连接失败后一秒内不再尝试，避免每条日志都进行连接
+		s.retryAt = time.Now().Add(time.Second)
+		return err
+	}
+
+	s.conn = conn
+	return nil
+}
+
+func (s *SyslogWriter) closeConn() {
+	if s.conn != nil {
+		_ = s.conn.Close()
+		s.conn = nil
+	}
+}
+
+func (s *SyslogWriter) Write(p []byte) (n int, err error) {
+	return s.WriteLevel(Disable, p)
+}
+
+//Failed 返回发送失败的记录条数，错误由Logger报告
+func (s *SyslogWriter) Failed() uint64 {
+	return atomic.LoadUint64(&s.failed)
+}
+
+//WriteLevel 按记录的等级得到syslog的严重程度，并发送此记录
+func (s *SyslogWriter) WriteLevel(level Level, p []byte) (n int, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	defer func() {
+		if err != nil {
+			atomic.AddUint64(&s.failed, 1)
+		}
+	}()
+
+	s.buffer = s.appendMessage(s.buffer[:0], level, p)
+
+	//发送失败时重新连接并再发送一次
+	for i := 0; i < 2; i++ {
+		if s.conn == nil {
+			if time.Now().Before(s.retryAt) {
+				return 0, NotConnected("syslog:" + s.address)
+			}
+			if err = s.connect(); err != nil {
+				return 0, err
+			}
+		}
+
+		//对端不再接收时不会一直阻塞
+		_ = s.conn.SetWriteDeadline(time.Now().Add(syslogTimeout))
+		if _, err = s.conn.Write(s.appendFrame(s.frame[:0], s.buffer)); err == nil {
+			s.addWritten(len(p))
+			return len(p), nil
+		}
+		s.closeConn()
+	}
+
+	return 0, err
+}
+
+//appendFrame 按连接的类型对消息分帧，数据报不需要分帧
+func (s *SyslogWriter) appendFrame(buf []byte, msg []byte) []byte {
+	switch {
+	case s.network == "tcp" || s.network == "tls":
+		//RFC6587 使用字节数作为帧的前缀
+		buf = strconv.AppendInt(buf, int64(len(msg)), 10)
+		buf = append(buf, ' ')
+		buf = append(buf, msg...)
+	case s.stream:
+		buf = append(buf, msg...)
+		buf = append(buf, '\n')
+	default:
+		buf = append(buf, msg...)
+	}
+	s.frame = buf
+
+	return buf
+}
+
+//appendMessage 生成一条完整的syslog消息
+func (s *SyslogWriter) appendMessage(buf []byte, level Level, p []byte) []byte {
+	//去掉Pattern生成的结尾换行
+	for len(p) > 0 && (p[len(p)-1] == '\n' || p[len(p)-1] == '\r') {
+		p = p[:len(p)-1]
+	}
+
+	var now = time.Now()
+
+	buf = append(buf, '<')
+	buf = strconv.AppendInt(buf, int64(int(s.facility)*8+severity(level)), 10)
+	buf = append(buf, '>')
+
+	switch s.format {
+	case RFC3164:
+		buf = now.AppendFormat(buf, time.Stamp)
+		buf = append(buf, ' ')
+		if s.network != "unix" {
+			buf = append(buf, nilValue(s.hostname)...)
+			buf = append(buf, ' ')
+		}
+		buf = append(buf, s.appName...)
+		buf = append(buf, '[')
+		buf = strconv.AppendInt(buf, int64(os.Getpid()), 10)
+		buf = append(buf, "]: "...)
+	default:
+		buf = append(buf, "1 "...)
+		buf = now.AppendFormat(buf, "2006-01-02T15:04:05.000000Z07:00")
+		buf = append(buf, ' ')
+		buf = append(buf, nilValue(s.hostname)...)
+		buf = append(buf, ' ')
+		buf = append(buf, nilValue(s.appName)...)
+		buf = append(buf, ' ')
+		buf = strconv.AppendInt(buf, int64(os.Getpid()), 10)
+		buf = append(buf, " - - "...)
+	}
+
+	return append(buf, p...)
+}
+
+func nilValue(s string) string {
+	if s == "" {
+		return "-"
+	}
+
+	return s
+}
+
+func (s *SyslogWriter) Close() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.closeConn()
+}
+
+func (*SyslogWriter) Flush() {
+}
+
+//SetConfig 设置相关参数
+func (s *SyslogWriter) SetConfig(config interface{}) error {
+	if config == nil {
+		return NotUnderstand("SyslogWriter:WriterPara")
+	}
+
+	var conf map[string]interface{}
+	switch config.(type) {
+	case map[string]interface{}:
+		conf = config.(map[string]interface{})
+	default:
+		return &MistakeType{"map[string]interface {} type", ""}
+	}
+
+	var strs = map[string]string{
+		"Network":    "unix",
+		"Address":    "",
+		"AppName":    "",
+		"Hostname":   "",
+		"Format":     "rfc5424",
+		"Facility":   "user",
+		"CAFile":     "",
+		"ServerName": "",
+	}
+	for key := range strs {
+		if val, ok := conf[key]; ok {
+			switch val.(type) {
+			case string:
+				strs[key] = val.(string)
+			case float64:
+				//Facility可直接使用数字
+				if key != "Facility" {
+					return &MistakeType{"string type", key}
+				}
+				strs[key] = strconv.Itoa(int(val.(float64)))
+			default:
+				return &MistakeType{"string type", key}
+			}
+		}
+	}
+
+	var facility, ok = refFacility[strings.ToLower(strs["Facility"])]
+	if !ok {
+		v, err := strconv.Atoi(strs["Facility"])
+		if err != nil || v < 0 || v > 23 {
+			return NotUnderstand("Facility:" + strs["Facility"])
+		}
+		facility = Facility(v)
+	}
+
+	var format SyslogFormat
+	switch strings.ToLower(strs["Format"]) {
+	case "rfc5424":
+		format = RFC5424
+	case "rfc3164":
+		format = RFC3164
+	default:
+		return NotUnderstand("Format:" + strs["Format"])
+	}
+
+	var network = strings.ToLower(strs["Network"])
+	if err := checkNetwork(network); err != nil {
+		return err
+	}
+
+	var tlsConfig *tls.Config
+	if network == "tls" {
+		tlsConfig = &tls.Config{ServerName: strs["ServerName"]}
+		if strs["CAFile"] != "" {
+			pem, err := ioutil.ReadFile(strs["CAFile"])
+			if err != nil {
+				return NotFoundFile(strs["CAFile"])
+			}
+			tlsConfig.RootCAs = x509.NewCertPool()
+			if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+				return NotUnderstand("CAFile:" + strs["CAFile"])
+			}
+		}
+	}
+
+	var hostname, _ = os.Hostname()
+	if strs["Hostname"] != "" {
+		hostname = strs["Hostname"]
+	}
+	var appName = strs["AppName"]
+	if appName == "" {
+		appName = os.Args[0][strings.LastIndexByte(os.Args[0], '/')+1:]
+	}
+	var address = strs["Address"]
+	if network == "unix" && address == "" {
+		address = "/dev/log"
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.closeConn()
+	s.network = network
+	s.address = address
+	s.facility = facility
+	s.appName = appName
+	s.hostname = hostname
+	s.format = format
+	s.tlsConfig = tlsConfig
+	s.buffer = make([]byte, 0, 1024)
+
+	//连接失败时不影响配置的加载，写入时将自动重连
+	_ = s.connect()
+
+	return nil
+}
@@ -0,0 +1,128 @@
+package onelog
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSyslogWriterUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer pc.Close()
+
+	sw, err := NewSyslogWriter("udp", pc.LocalAddr().String(), refFacility["local3"], "onelog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var log = New(sw, InfoLevel, &JsonPattern{})
+	defer log.Close()
+
+	log.Error().Int("id", 1).Msg("udp")
+
+	var buf = make([]byte, 2048)
+	_ = pc.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var msg = string(buf[:n])
+	//local3(19)*8 + error(3)
+	if !strings.HasPrefix(msg, "<155>1 ") || !strings.Contains(msg, " onelog ") || strings.HasSuffix(msg, "\n") {
+		t.Error(msg)
+	}
+}
+
+func TestSyslogWriterTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer ln.Close()
+
+	var received = make(chan string, 4)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			r := bufio.NewReader(conn)
+			for {
+				length, err := r.ReadString(' ')
+				if err != nil {
+					break
+				}
+				var size int
+				for _, c := range strings.TrimSpace(length) {
+					size = size*10 + int(c-'0')
+				}
+				var msg = make([]byte, size)
+				if _, err = io.ReadFull(r, msg); err != nil {
+					break
+				}
+				received <- string(msg)
+			}
+			_ = conn.Close()
+		}
+	}()
+
+	sw := &SyslogWriter{}
+	err = sw.SetConfig(map[string]interface{}{
+		"Network":  "tcp",
+		"Address":  ln.Addr().String(),
+		"Format":   "rfc3164",
+		"AppName":  "app",
+		"Hostname": "host",
+		"Facility": float64(1),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sw.Close()
+
+	_, _ = sw.WriteLevel(WarnLevel, []byte("hello\n"))
+
+	select {
+	case msg := <-received:
+		if !strings.HasPrefix(msg, "<12>") || !strings.Contains(msg, " host app[") || !strings.HasSuffix(msg, "]: hello") {
+			t.Error(msg)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("未收到消息")
+	}
+}
+
+func TestSyslogWriterFailed(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	var address = ln.Addr().String()
+	_ = ln.Close()
+
+	sw, err := NewSyslogWriter("tcp", address, refFacility["user"], "onelog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sw.Close()
+
+	for i := 0; i < 3; i++ {
+		if _, err = sw.Write([]byte("lost")); err == nil {
+			t.Fatal("未连接时应返回错误")
+		}
+	}
+	//等待重连期间不再尝试连接
+	if _, ok := err.(NotConnected); !ok {
+		t.Errorf("等待重连时应返回NotConnected:%T %v", err, err)
+	}
+	if sw.Failed() != 3 || sw.stats().Errors != 3 {
+		t.Errorf("失败条数不正确:%d %+v", sw.Failed(), sw.stats())
+	}
+}
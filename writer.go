@@ -1,14 +1,13 @@
 package onelog
 
 import (
-	"compress/gzip"
-	"fmt"
 	"io"
 	"os"
-	"reflect"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 )
@@ -19,6 +18,70 @@ type Writer interface {
 	SetConfig(config interface{}) error
 }
 
+//Flusher 带有缓存的Writer可实现此接口，Logger.Flush与高等级的记录将调用Flush
+type Flusher interface {
+	//Flush 将缓存中的内容立即写入
+	Flush()
+}
+
+//flush 对实现了Flusher的Writer进行Flush
+func flush(writer Writer) {
+	if f, ok := writer.(Flusher); ok {
+		f.Flush()
+	}
+}
+
+//soonFlusher 在后台写出的Writer，只通知其尽快写出，不等待写出完成
+type soonFlusher interface {
+	flushSoon()
+}
+
+//flushSoon 高等级的记录写入后调用。本地缓存的Writer立即Flush，异步与网络的Writer只通知其尽快写出，调用方不会被阻塞
+func flushSoon(writer Writer) {
+	if s, ok := writer.(soonFlusher); ok {
+		s.flushSoon()
+		return
+	}
+
+	flush(writer)
+}
+
+//LevelAwareWriter 可感知日志等级的Writer。实现此接口的Writer在写入时将同时得到此条记录的等级
+type LevelAwareWriter interface {
+	WriteLevel(level Level, p []byte) (n int, err error)
+}
+
+//writeLevel 向writer写入一条记录，如writer实现了LevelAwareWriter，将同时传入记录的等级
+func writeLevel(writer Writer, level Level, p []byte) (n int, err error) {
+	if lw, ok := writer.(LevelAwareWriter); ok {
+		return lw.WriteLevel(level, p)
+	}
+
+	return writer.Write(p)
+}
+
+//eachWriter 依次访问writer以及其包装的所有Writer
+func eachWriter(writer Writer, fn func(Writer)) {
+	fn(writer)
+
+	switch w := writer.(type) {
+	case *MultipleWriter:
+		for curr := w; curr != nil && curr.Writer != nil; curr = curr.Next {
+			eachWriter(curr.Writer, fn)
+		}
+	case *LevelFilter:
+		eachWriter(w.Writer, fn)
+	case *AsyncWriter:
+		eachWriter(w.writer, fn)
+	case *HealthWriter:
+		eachWriter(w.Writer, fn)
+	case *FailoverWriter:
+		for _, fw := range w.Writers {
+			eachWriter(fw, fn)
+		}
+	}
+}
+
 type MultipleWriter struct {
 	Writer Writer
 	Next   *MultipleWriter
@@ -33,6 +96,29 @@ type FileWriter struct {
 	len         int
 	mutex       sync.Mutex
 	date        string
+	schedule    RotateSchedule
+	periodStart time.Time
+	nextRotate  time.Time
+	retention   *RetentionPolicy
+	//retentionMutex 保证同一时间只有一个清理归档文件的操作
+	retentionMutex sync.Mutex
+	sync           bool
+	stopFlush      chan struct{}
+	//lock 多进程模式下使用的锁文件，为nil时不与其他进程协调
+	lock   *os.File
+	codec  Codec
+	naming archiveNaming
+	//link 指向当前日志文件的符号链接
+	link string
+	//activeName 当前日志文件名的模板，为空时使用fileName
+	activeName string
+	//active 当前打开的日志文件的路径
+	active  string
+	signals chan os.Signal
+	//closed 已调用Close。file为nil而closed为false时，为切分后未能创建日志文件，之后的写入将重新尝试
+	closed bool
+	errorReporter
+	writerCounters
 }
 
 type Stdout struct {
@@ -40,10 +126,18 @@ type Stdout struct {
 }
 
 func (s *Stdout) Write(p []byte) (n int, err error) {
-	return s.Writer.Write(p)
+	n, err = s.Writer.Write(p)
+	s.count(n, err)
+
+	return n, err
 }
 
-func (*Stdout) Close() {
+//Close 不关闭其Writer，只去掉统计
+func (s *Stdout) Close() {
+	stdoutCounters.Delete(s)
+}
+
+func (*Stdout) Flush() {
 }
 
 //SetConfig 设置相关参数
@@ -92,18 +186,41 @@ func NewMultipleWriter(writers ...Writer) *MultipleWriter {
 }
 
 func (m *MultipleWriter) Write(p []byte) (n int, err error) {
-	if m.Writer != nil {
-		var curr = m
-		for ; curr != nil; curr = curr.Next {
-			if n, err = curr.Writer.Write(p); err != nil {
-				return
-			}
-		}
-	} else {
+	return m.WriteLevel(Disable, p)
+}
+
+//WriteLevel 带等级的写入，等级将继续传递给每一个下级Writer。某一个Writer失败时仍然写入其后的Writer，
+//最后返回所有的错误，暂停使用中的Writer不作为错误。所有的Writer都暂停使用时返回AllBenched
+func (m *MultipleWriter) WriteLevel(level Level, p []byte) (n int, err error) {
+	if m.Writer == nil {
 		return 0, NotNil("未找到对象")
 	}
 
-	return len(p), nil
+	var errs WriteErrors
+	var written = false
+	for curr := m; curr != nil; curr = curr.Next {
+		if _, err = writeLevel(curr.Writer, level, p); err == nil {
+			written = true
+			continue
+		}
+		switch err.(type) {
+		case Benched, AllBenched:
+		default:
+			errs = append(errs, namedError(curr.Writer, err))
+		}
+	}
+
+	switch {
+	case written && len(errs) == 0:
+		return len(p), nil
+	case written:
+		return len(p), errs
+	case len(errs) == 0:
+		//记录没有写入任何Writer
+		return 0, AllBenched("MultipleWriter")
+	}
+
+	return 0, errs
 }
 
 func (m *MultipleWriter) Close() {
@@ -113,6 +230,20 @@ func (m *MultipleWriter) Close() {
 	}
 }
 
+func (m *MultipleWriter) Flush() {
+	var curr = m
+	for ; curr != nil && curr.Writer != nil; curr = curr.Next {
+		flush(curr.Writer)
+	}
+}
+
+func (m *MultipleWriter) flushSoon() {
+	var curr = m
+	for ; curr != nil && curr.Writer != nil; curr = curr.Next {
+		flushSoon(curr.Writer)
+	}
+}
+
 //SetConfig 设置相关参数
 func (m *MultipleWriter) SetConfig(config interface{}) error {
 	if config != nil {
@@ -123,17 +254,10 @@ func (m *MultipleWriter) SetConfig(config interface{}) error {
 				case map[string]interface{}:
 					var rec = config.([]interface{})[i].(map[string]interface{})
 
-					writer, ok := rec["Writer"]
-					if !ok {
-						return NotNil("Writer")
+					w, err := newWriterFromConfig(rec)
+					if err != nil {
+						return err
 					}
-					writerPara, ok := rec["WriterPara"]
-					if !ok {
-						return NotNil("WriterPara")
-					}
-
-					w := reflect.New(reflect.TypeOf(refWriter[writer.(string)])).Interface().(Writer)
-					w.SetConfig(writerPara)
 
 					if m.Writer == nil {
 						m.Writer = w
@@ -168,16 +292,155 @@ func NewFileWriter(fileName string, maxCapacity int64) (*FileWriter, error) {
 	return &FileWriter{
 		file:        file,
 		fileName:    fileName,
+		active:      fileName,
 		maxCapacity: maxCapacity,
 		buffer:      b,
 		len:         0,
-		mutex:       sync.Mutex{},
+		codec:       refCodec["gzip"],
 	}, nil
 }
 
+//SetCodec 设置切分后归档文件的压缩方式，默认为gzip
+func (w *FileWriter) SetCodec(codec Codec) *FileWriter {
+	if codec == nil {
+		codec = NoCompression{}
+	}
+
+	w.mutex.Lock()
+	w.codec = codec
+	w.mutex.Unlock()
+
+	return w
+}
+
+//SetRotateSchedule 设置按时间切分的计划，可与按大小切分同时使用。参数为nil时取消按时间切分
+func (w *FileWriter) SetRotateSchedule(schedule RotateSchedule) *FileWriter {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.schedule = schedule
+	if schedule != nil {
+		var now = time.Now()
+		w.periodStart = lastBoundary(schedule, now)
+		w.nextRotate = schedule.Next(now)
+	}
+
+	return w
+}
+
+//SetSync 设置每次Flush时是否同时调用fsync，保证内容真正写入磁盘
+func (w *FileWriter) SetSync(enable bool) *FileWriter {
+	w.mutex.Lock()
+	w.sync = enable
+	w.mutex.Unlock()
+
+	return w
+}
+
+//SetFlushInterval 设置后台定时Flush的间隔，interval小于等于0时停止定时Flush
+func (w *FileWriter) SetFlushInterval(interval time.Duration) *FileWriter {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.stopFlush != nil {
+		close(w.stopFlush)
+		w.stopFlush = nil
+	}
+
+	if interval > 0 {
+		w.stopFlush = make(chan struct{})
+		go w.flushLoop(interval, w.stopFlush)
+	}
+
+	return w
+}
+
+func (w *FileWriter) flushLoop(interval time.Duration, stop chan struct{}) {
+	var ticker = time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			w.Flush()
+		case <-stop:
+			return
+		}
+	}
+}
+
+//Flush 将缓存中的内容写入文件，如设置了Sync将同时调用fsync
+func (w *FileWriter) Flush() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.closed {
+		return
+	}
+	if err := w.openFile(); err != nil {
+		w.report("FileWriter", "open", w.active, err)
+		return
+	}
+
+	w.checkRotateTime()
+	w.writeToDisk(false)
+
+	if w.sync {
+		w.report("FileWriter", "sync", w.fileName, w.file.Sync())
+	}
+}
+
 //Close 当程序关闭时调用的操作
 func (w *FileWriter) Close() {
-	w.writeToDisk(true)
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.closed {
+		return
+	}
+
+	if w.stopFlush != nil {
+		close(w.stopFlush)
+		w.stopFlush = nil
+	}
+	w.stopReopenSignal()
+
+	//切分后未能创建日志文件时，最后再尝试一次以写入缓存中的记录
+	if w.active != "" {
+		w.report("FileWriter", "open", w.active, w.openFile())
+	}
+	if w.file != nil {
+		w.writeToDisk(true)
+	}
+	if w.file != nil {
+		if w.sync {
+			w.report("FileWriter", "sync", w.fileName, w.file.Sync())
+		}
+		_ = w.file.Close()
+		w.file = nil
+	}
+
+	w.closed = true
+	w.closeLock()
+}
+
+//openFile 切分后未能创建日志文件时重新打开，已打开时不做处理，调用方需要持有锁
+func (w *FileWriter) openFile() error {
+	if w.file != nil {
+		return nil
+	}
+	if w.closed || w.active == "" {
+		return Closed("FileWriter")
+	}
+
+	f, err := createLogWriteFile(w.active)
+	if err != nil {
+		return err
+	}
+	w.file = f
+	w.report("FileWriter", "link", w.link, w.updateLink())
+
+	return nil
 }
 
 //SetConfig 设置相关参数
@@ -188,9 +451,10 @@ func (w *FileWriter) SetConfig(config interface{}) error {
 		default:
 			return &MistakeType{"map[string]interface {} type", ""}
 		}
+		var conf = config.(map[string]interface{})
 		var filePath = ""
 
-		if val, ok := config.(map[string]interface{})["LogsRoot"]; ok {
+		if val, ok := conf["LogsRoot"]; ok {
 			switch val.(type) {
 			case string:
 				filePath = strings.TrimRight(val.(string), "/")
@@ -208,7 +472,9 @@ func (w *FileWriter) SetConfig(config interface{}) error {
 			}
 		}
 
-		if val, ok := config.(map[string]interface{})["FileName"]; ok {
+		var logsRoot = filePath
+
+		if val, ok := conf["FileName"]; ok {
 			switch val.(type) {
 			case string:
 				if val == "" {
@@ -221,32 +487,211 @@ func (w *FileWriter) SetConfig(config interface{}) error {
 			}
 		}
 
-		if val, ok := config.(map[string]interface{})["MaxCapacity"]; ok {
+		var maxCapacity = 0
+		if val, ok := conf["MaxCapacity"]; ok {
 			switch val.(type) {
 			case float64:
-				v := int(val.(float64))
-				if v <= 0 {
-					return &MistakeType{"大于0", strconv.Itoa(v)}
+				maxCapacity = int(val.(float64))
+				if maxCapacity <= 0 {
+					return &MistakeType{"大于0", strconv.Itoa(maxCapacity)}
 				}
+			default:
+				return &MistakeType{"number type", ""}
+			}
+		}
 
-				newF, err := NewFileWriter(filePath, int64(v)*1024*1024)
-				if err != nil {
+		var rotateUTC = false
+		if val, ok := conf["RotateUTC"]; ok {
+			switch val.(type) {
+			case bool:
+				rotateUTC = val.(bool)
+			default:
+				return &MistakeType{"bool type", ""}
+			}
+		}
+
+		var schedule RotateSchedule
+		if val, ok := conf["Rotate"]; ok {
+			switch val.(type) {
+			case string:
+				var err error
+				if schedule, err = NewRotateSchedule(val.(string), rotateUTC); err != nil {
 					return err
 				}
+			default:
+				return &MistakeType{"string type", ""}
+			}
+		}
+
+		var retention *RetentionPolicy
+		for _, key := range []string{"MaxArchives", "MaxAge", "MaxTotalSize"} {
+			val, ok := conf[key]
+			if !ok {
+				continue
+			}
+
+			var v float64
+			switch val.(type) {
+			case float64:
+				v = val.(float64)
+				if v <= 0 {
+					return &MistakeType{"大于0", strconv.FormatFloat(v, 'f', -1, 64)}
+				}
+			default:
+				return &MistakeType{"number type", ""}
+			}
+
+			if retention == nil {
+				retention = &RetentionPolicy{}
+			}
+
+			switch key {
+			case "MaxArchives":
+				retention.MaxArchives = int(v)
+			case "MaxAge":
+				//以天为单位
+				retention.MaxAge = time.Duration(v * float64(24*time.Hour))
+			case "MaxTotalSize":
+				//与MaxCapacity一样以M为单位
+				retention.MaxTotalSize = int64(v * 1024 * 1024)
+			}
+		}
+
+		var flushInterval time.Duration
+		if val, ok := conf["FlushInterval"]; ok {
+			switch val.(type) {
+			case float64:
+				//以毫秒为单位
+				flushInterval = time.Duration(val.(float64)) * time.Millisecond
+			default:
+				return &MistakeType{"number type", ""}
+			}
+		}
 
-				w.fileName = newF.fileName
-				w.file = newF.file
-				w.len = newF.len
-				w.saveIndex = newF.saveIndex
-				w.buffer = newF.buffer
-				w.mutex = newF.mutex
-				w.maxCapacity = newF.maxCapacity
+		var fsync = false
+		if val, ok := conf["Sync"]; ok {
+			switch val.(type) {
+			case bool:
+				fsync = val.(bool)
+			default:
+				return &MistakeType{"bool type", ""}
+			}
+		}
 
-				return nil
+		var compressLevel = -1
+		if val, ok := conf["CompressLevel"]; ok {
+			switch val.(type) {
+			case float64:
+				compressLevel = int(val.(float64))
+				if compressLevel < 0 {
+					return &MistakeType{"CompressLevel:大于0", strconv.Itoa(compressLevel)}
+				}
+			default:
+				return &MistakeType{"number type", ""}
+			}
+		}
+
+		var codec = refCodec["gzip"]
+		if val, ok := conf["Compress"]; ok {
+			switch val.(type) {
+			case string:
+				var err error
+				if codec, err = codecByName(val.(string), compressLevel); err != nil {
+					return err
+				}
 			default:
 				return &MistakeType{"string type", ""}
 			}
+		} else if compressLevel >= 0 {
+			var err error
+			if codec, err = codecByName("gzip", compressLevel); err != nil {
+				return err
+			}
+		}
+
+		var names = map[string]string{"ArchiveName": "", "ArchiveDir": "", "ActiveName": "", "CurrentLink": "", "ReopenSignal": ""}
+		for key := range names {
+			if val, ok := conf[key]; ok {
+				switch val.(type) {
+				case string:
+					names[key] = val.(string)
+				default:
+					return &MistakeType{"string type", key}
+				}
+			}
+		}
+		if err := checkArchiveName(names["ArchiveName"]); err != nil {
+			return err
+		}
+		if strings.ContainsAny(names["ActiveName"], `/\`) {
+			return NotUnderstand("ActiveName:" + names["ActiveName"])
+		}
+		//相对路径的归档目录位于LogsRoot之下
+		if dir := names["ArchiveDir"]; dir != "" && !filepath.IsAbs(dir) && logsRoot != "" {
+			names["ArchiveDir"] = filepath.Join(logsRoot, dir)
+		}
+		if link := names["CurrentLink"]; link != "" && !filepath.IsAbs(link) && logsRoot != "" {
+			names["CurrentLink"] = filepath.Join(logsRoot, link)
+		}
+
+		var reopenSignal os.Signal
+		if name := names["ReopenSignal"]; name != "" {
+			var ok bool
+			if reopenSignal, ok = refSignal[strings.ToUpper(name)]; !ok {
+				return NotUnderstand("ReopenSignal:" + name)
+			}
+		}
+
+		var multiProcess = false
+		if val, ok := conf["MultiProcess"]; ok {
+			switch val.(type) {
+			case bool:
+				multiProcess = val.(bool)
+			default:
+				return &MistakeType{"bool type", ""}
+			}
+		}
+
+		//按大小或按时间切分至少需要指定一个
+		if maxCapacity == 0 && schedule == nil {
+			return NotNil("MaxCapacity")
+		}
+
+		newF, err := NewFileWriter(filePath, int64(maxCapacity)*1024*1024)
+		if err != nil {
+			return err
+		}
+
+		w.fileName = newF.fileName
+		w.active = newF.active
+		w.file = newF.file
+		w.len = newF.len
+		w.saveIndex = newF.saveIndex
+		w.buffer = newF.buffer
+		w.maxCapacity = newF.maxCapacity
+		w.SetCodec(codec)
+		if err = w.SetArchiveName(names["ArchiveName"]); err != nil {
+			return err
+		}
+		if err = w.SetArchiveDir(names["ArchiveDir"]); err != nil {
+			return err
+		}
+		//需要在SetRotateSchedule之后，文件名使用切分计划的时区
+		w.SetRotateSchedule(schedule)
+		if err = w.SetActiveName(names["ActiveName"]); err != nil {
+			return err
+		}
+		if err = w.SetCurrentLink(names["CurrentLink"]); err != nil {
+			return err
 		}
+		if reopenSignal != nil {
+			w.ReopenOnSignal(reopenSignal)
+		}
+		w.SetRetention(retention)
+		w.SetSync(fsync)
+		w.SetFlushInterval(flushInterval)
+
+		return w.SetMultiProcess(multiProcess)
 	}
 
 	return NotUnderstand("WriterPara")
@@ -256,64 +701,163 @@ func (w *FileWriter) Write(p []byte) (n int, err error) {
 	w.mutex.Lock()
 	defer w.mutex.Unlock()
 
+	//切分后未能创建日志文件时在此重新尝试，失败时返回错误，由Logger交给错误处理
+	if err = w.openFile(); err != nil {
+		return 0, err
+	}
+
+	w.checkRotateTime()
+
 	if len(p) > cap(w.buffer)-w.len {
 		w.writeToDisk(false)
 	}
 
 	copy(w.buffer[w.len:], p)
 	w.len += len(p)
+	w.addWritten(len(p))
 
 	return len(p), nil
 }
 
+//checkRotateTime 已经跨过了切分时间点时，先将缓存写入旧文件再切分
+func (w *FileWriter) checkRotateTime() {
+	if w.schedule != nil && !time.Now().Before(w.nextRotate) {
+		//多进程模式下在持有文件锁时切分
+		if w.lock != nil {
+			w.lockedWriteToDisk(false)
+			return
+		}
+
+		w.writeBuffer()
+		w.rotateByTime(false)
+	}
+}
+
+//rotateByTime 到达切分时间点时进行切分，空文件不进行归档
+func (w *FileWriter) rotateByTime(isClose bool) {
+	if this, e := os.Stat(w.active); e == nil && this.Size() > 0 {
+		w.rotate(isClose)
+	} else if !isClose {
+		//空文件不归档，但文件名中的日期需要更新
+		w.openActive()
+	}
+
+	//长时间没有写入时跨过了多个时间段，新的时间段从now之前最近的切分时间点开始
+	var now = time.Now()
+	w.periodStart = lastBoundary(w.schedule, now)
+	w.nextRotate = w.schedule.Next(now)
+}
+
 func (w *FileWriter) writeToDisk(isClose bool) {
-	if this, e := os.Stat(w.file.Name()); e == nil {
-		if this.Size() > w.maxCapacity {
-			var date = time.Now().Format(".0102_")
-			if date != w.date {
-				w.date = date
-				w.saveIndex = 0
-			}
+	if w.lock != nil {
+		w.lockedWriteToDisk(isClose)
+		return
+	}
 
-			buf := make([]byte, 200)[0:0]
+	if w.maxCapacity > 0 {
+		if this, e := os.Stat(w.active); e == nil && this.Size() > w.maxCapacity {
+			w.rotate(isClose)
+		}
+	}
 
-			buf = append(buf, w.fileName...)
-			buf = append(buf, w.date...)
-			index := len(buf)
+	w.writeBuffer()
+}
 
-			for true {
-				w.saveIndex++
-				buf = strconv.AppendInt(buf[:index], int64(w.saveIndex), 10)
+//rotate 将当前文件改名为归档文件并压缩，然后重新创建日志文件
+func (w *FileWriter) rotate(isClose bool) {
+	//按时间切分时使用时间段的开始作为名称，否则使用当天的日期，包含年份以免不同年份的归档重名
+	var t = time.Now()
+	var label = t.Format("20060102")
+	if w.schedule != nil {
+		t = w.periodStart.In(scheduleLocation(w.schedule))
+		label = w.schedule.Label(w.periodStart)
+	}
 
-				if exists(string(buf) + ".gz") {
-					continue
-				}
-				break
-			}
-			tempName := string(buf)
-
-			_ = w.file.Close()
-			_ = os.Rename(w.fileName, tempName)
-			w.file, _ = createLogWriteFile(w.fileName)
-
-			//判断是否最后的结束，如果是最后结束，在压缩结束后将文件关闭
-			if isClose {
-				gzipFile(tempName)
-				_ = w.file.Close()
-			} else {
-				go gzipFile(tempName)
-			}
+	//模板生成的名称除序号外相同时，序号继续递增
+	var ext = w.codec.Extension()
+	if key := w.naming.path(w.fileName, t, label, "{seq}", ext); key != w.date {
+		w.date = key
+		w.saveIndex = 0
+	}
+
+	var archive, tempName string
+	for true {
+		w.saveIndex++
+		archive = w.naming.path(w.fileName, t, label, strconv.Itoa(w.saveIndex), ext)
+		tempName = uncompressedName(archive, ext)
+
+		//其他进程切分出的文件可能尚未压缩完成
+		if exists(archive) || exists(tempName) {
+			continue
 		}
+		break
 	}
 
-	_, _ = w.file.Write(w.buffer[:w.len])
-	w.len = 0
+	_ = w.file.Close()
+	var err = os.Rename(w.active, tempName)
+	w.report("FileWriter", "rename", w.active, err)
+	if err == nil {
+		atomic.AddUint64(&w.rotations, 1)
+	}
+
+	var e error
+	w.active = w.activePath()
+	//创建失败时file为nil，之后的写入将重新尝试
+	if w.file, e = createLogWriteFile(w.active); e != nil {
+		w.report("FileWriter", "open", w.active, e)
+	}
+	w.report("FileWriter", "link", w.link, w.updateLink())
+	if err != nil {
+		return
+	}
+
+	//如果是最后结束，需要等待压缩完成
+	var codec, policy, naming = w.codec, w.retention, w.naming
+	if isClose {
+		w.archive(tempName, archive, codec, policy, naming)
+	} else {
+		submitArchive(func() {
+			w.archive(tempName, archive, codec, policy, naming)
+		})
+	}
 }
 
-//gzipFile 压缩文件，并删除原有的文件
-func gzipFile(fileName string) {
-	_ = CompressFile(fileName, fileName+".gz")
-	_ = os.Remove(fileName)
+//archive 将切分出来的文件压缩为归档文件，完成后按保留策略清理归档文件
+func (w *FileWriter) archive(fileName, archive string, codec Codec, policy *RetentionPolicy, naming archiveNaming) {
+	w.report("FileWriter", "compress", fileName, compressArchive(fileName, archive, codec))
+	w.enforceRetention(policy, codec, naming)
+}
+
+//compressArchive 压缩文件，压缩成功后删除原有的文件。不压缩时保留原文件作为归档文件
+func compressArchive(fileName, archive string, codec Codec) error {
+	if codec.Extension() == "" {
+		return nil
+	}
+
+	if err := compressFile(codec, fileName, archive); err != nil {
+		_ = os.Remove(archive)
+		return err
+	}
+
+	return os.Remove(fileName)
+}
+
+//writeBuffer 将缓存写入当前文件，调用方需要持有锁
+func (w *FileWriter) writeBuffer() {
+	if w.len == 0 {
+		return
+	}
+
+	//切分后未能创建日志文件且再次尝试仍失败时，缓存中的记录将被丢弃
+	if err := w.openFile(); err != nil {
+		w.report("FileWriter", "write", w.fileName, err)
+		w.len = 0
+		return
+	}
+
+	_, err := w.file.Write(w.buffer[:w.len])
+	w.report("FileWriter", "write", w.fileName, err)
+	w.len = 0
 }
 
 //exists 判断所给路径文件/文件夹是否存在
@@ -339,23 +883,12 @@ func createLogWriteFile(name string) (*os.File, error) {
 
 //CompressFile 使用gzip压缩成gz
 func CompressFile(fileName string, dest string) error {
-	d, _ := os.Create(dest)
-	defer d.Close()
-	gw := gzip.NewWriter(d)
-	defer gw.Close()
-	file, err := os.Open(fileName)
-	defer file.Close()
+	err := compressFile(refCodec["gzip"], fileName, dest)
 	if err != nil {
-		fmt.Printf("出错了，%v", err)
-		return err
-	}
-	_, _ = io.Copy(gw, file)
-	if err != nil {
-		fmt.Printf("出错了，%v", err)
-		return err
+		reportError(&WriterError{"FileWriter", "compress", fileName, err})
 	}
 
-	return nil
+	return err
 }
 
 func checkDir(path string) error {
@@ -0,0 +1,121 @@
+package onelog
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileWriterFlush(t *testing.T) {
+	var name = filepath.Join(t.TempDir(), "flush.log")
+	fw, err := NewFileWriter(name, 1024*1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var log = New(fw, InfoLevel, &JsonPattern{})
+	defer log.Close()
+
+	log.Info().Msg("buffered")
+	if b, _ := ioutil.ReadFile(name); len(b) != 0 {
+		t.Error("INFO记录不应立即写入文件")
+	}
+
+	log.Flush()
+	if b, _ := ioutil.ReadFile(name); len(b) == 0 {
+		t.Error("Flush后记录应写入文件")
+	}
+
+	//ERROR记录在Msg返回前写入
+	log.Error().Msg("flushed")
+	if b, _ := ioutil.ReadFile(name); !strings.Contains(string(b), "flushed") {
+		t.Error("ERROR记录应立即写入文件")
+	}
+
+	fw.SetFlushInterval(10 * time.Millisecond)
+	log.Info().Msg("interval")
+	time.Sleep(50 * time.Millisecond)
+	if b, _ := ioutil.ReadFile(name); !strings.Contains(string(b), "interval") {
+		t.Error("定时Flush未生效")
+	}
+}
+
+//failOpen 模拟切分后未能创建日志文件：原文件已被改名，同名的位置为一个目录
+func failOpen(t *testing.T, fw *FileWriter) {
+	fw.mutex.Lock()
+	defer fw.mutex.Unlock()
+
+	_ = fw.file.Close()
+	fw.file = nil
+	if err := os.Rename(fw.active, fw.active+".old"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(fw.active, 0777); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileWriterReopenAfterFailure(t *testing.T) {
+	var name = filepath.Join(t.TempDir(), "fail.log")
+	fw, err := NewFileWriter(name, 1024*1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	failOpen(t, fw)
+	var closed Closed
+	if _, err = fw.Write([]byte("lost\n")); err == nil || errors.As(err, &closed) {
+		t.Fatalf("未能创建文件时应返回打开的错误:%v", err)
+	}
+
+	//可以创建文件后恢复写入
+	_ = os.Remove(name)
+	if _, err = fw.Write([]byte("recovered\n")); err != nil {
+		t.Fatal(err)
+	}
+	fw.Flush()
+	if b, _ := ioutil.ReadFile(name); string(b) != "recovered\n" {
+		t.Errorf("%q", b)
+	}
+
+	//未能创建文件时关闭，仍停止定时写入并释放锁文件
+	fw.SetFlushInterval(time.Hour)
+	if err = fw.SetMultiProcess(true); err != nil {
+		t.Fatal(err)
+	}
+	failOpen(t, fw)
+	fw.Close()
+	if fw.stopFlush != nil || fw.lock != nil || !fw.closed {
+		t.Error("关闭时应停止定时写入并释放锁文件")
+	}
+	if _, err = fw.Write([]byte("x")); !errors.As(err, &closed) {
+		t.Errorf("关闭后应返回Closed:%v", err)
+	}
+}
+
+//plainWriter 没有实现Flusher的Writer，与只实现了Writer接口的自定义Writer相同
+type plainWriter struct {
+	bytes.Buffer
+}
+
+func (*plainWriter) Close() {
+}
+
+func (*plainWriter) SetConfig(interface{}) error {
+	return nil
+}
+
+func TestWriterWithoutFlush(t *testing.T) {
+	var w = &plainWriter{}
+	var log = New(w, InfoLevel, &JsonPattern{})
+
+	log.Error().Msg("no flush")
+	log.Flush()
+	if !strings.Contains(w.String(), "no flush") {
+		t.Error(w.String())
+	}
+}
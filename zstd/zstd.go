@@ -0,0 +1,42 @@
+//Package zstd 为onelog的归档文件提供zstd压缩。导入此包后即可在配置文件中使用"Compress": "zstd"，
+//不需要zstd的程序不必引入其依赖
+package zstd
+
+import (
+	"io"
+	"strconv"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/udbjqrmna/onelog"
+)
+
+func init() {
+	onelog.RegisterCodec("zstd", Codec{})
+}
+
+//Codec zstd压缩，Level为zstd的压缩级别，0时使用默认级别
+type Codec struct {
+	Level int
+}
+
+func (Codec) Extension() string {
+	return "zst"
+}
+
+func (c Codec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	var level = zstd.SpeedDefault
+	if c.Level > 0 {
+		level = zstd.EncoderLevelFromZstd(c.Level)
+	}
+
+	return zstd.NewWriter(w, zstd.WithEncoderLevel(level))
+}
+
+//WithLevel 压缩级别为1~22
+func (c Codec) WithLevel(level int) (onelog.Codec, error) {
+	if level < 1 || level > 22 {
+		return nil, onelog.NotUnderstand("CompressLevel:" + strconv.Itoa(level))
+	}
+
+	return Codec{level}, nil
+}
@@ -0,0 +1,61 @@
+package zstd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/udbjqrmna/onelog"
+)
+
+func TestCodec(t *testing.T) {
+	codec, err := Codec{}.WithLevel(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	w, err := codec.NewWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = w.Write([]byte("content"))
+	_ = w.Close()
+
+	r, err := zstd.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if data, _ := ioutil.ReadAll(r); string(data) != "content" {
+		t.Errorf("内容不正确:%q", data)
+	}
+
+	for _, level := range []int{0, 23} {
+		if _, err = (Codec{}).WithLevel(level); err == nil {
+			t.Errorf("压缩级别%d应返回错误", level)
+		}
+	}
+}
+
+func TestRegistered(t *testing.T) {
+	var dir = t.TempDir()
+	var config = map[string]interface{}{
+		"FileName":      filepath.Join(dir, "a.log"),
+		"MaxCapacity":   float64(1),
+		"Compress":      "zstd",
+		"CompressLevel": float64(19),
+	}
+	var w = &onelog.FileWriter{}
+	if err := w.SetConfig(config); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	config["CompressLevel"] = float64(30)
+	if err := (&onelog.FileWriter{}).SetConfig(config); err == nil {
+		t.Error("超出范围的压缩级别应返回错误")
+	}
+}